@@ -12,7 +12,7 @@ func (cbk LoggingLexerCallback) Header(header midi.HeaderData) { fmt.Println("He
 func (cbk LoggingLexerCallback) Track(header midi.ChunkHeader) { fmt.Println("Track", header) }
 func (cbk LoggingLexerCallback) Began()                        { fmt.Println("Began") }
 func (cbk LoggingLexerCallback) Finished()                     { fmt.Println("Finished") }
-func (cbk LoggingLexerCallback) ErrorReading()                 { fmt.Println("ErrorReading") }
+func (cbk LoggingLexerCallback) ErrorReading(err error)        { fmt.Println("ErrorReading", err) }
 func (cbk LoggingLexerCallback) ErrorOpeningFile()             { fmt.Println("ErrorOpeningFile") }
 func (cbk LoggingLexerCallback) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32) {
 	fmt.Println("tempo", bpm, "bpm")
@@ -78,6 +78,15 @@ func (cbk LoggingLexerCallback) Undefined4(time uint32)    { fmt.Println("Undefi
 func (cbk LoggingLexerCallback) ActiveSensing(time uint32) { fmt.Println("ActiveSensing", time) }
 func (cbk LoggingLexerCallback) Reset(time uint32)         { fmt.Println("Reset", time) }
 func (cbk LoggingLexerCallback) Done(time uint32)          { fmt.Println("Done", time) }
+func (cbk LoggingLexerCallback) SysEx(data []byte, time uint32) {
+	fmt.Println("SysEx", data, time)
+}
+func (cbk LoggingLexerCallback) SysExContinuation(data []byte, time uint32) {
+	fmt.Println("SysExContinuation", data, time)
+}
+func (cbk LoggingLexerCallback) EscapeSequence(data []byte, time uint32) {
+	fmt.Println("EscapeSequence", data, time)
+}
 func (cbk LoggingLexerCallback) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
 	fmt.Println("SequenceNumber", channel, number, numberGiven, time)
 }
@@ -105,11 +114,26 @@ func (cbk LoggingLexerCallback) CuePointText(channel uint8, text string, time ui
 func (cbk LoggingLexerCallback) EndOfTrack(channel uint8, time uint32) {
 	fmt.Println("EndOfTrack", channel, time)
 }
+func (cbk LoggingLexerCallback) ChannelPrefix(channel uint8, time uint32) {
+	fmt.Println("ChannelPrefix", channel, time)
+}
+func (cbk LoggingLexerCallback) MidiPort(port uint8, time uint32) {
+	fmt.Println("MidiPort", port, time)
+}
 func (cbk LoggingLexerCallback) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
 	fmt.Println("TimeSignature", numerator, denomenator, clocksPerClick, demiSemiQuaverPerQuarter, time)
 }
-func (cbk LoggingLexerCallback) KeySignature(key midi.ScaleDegree, mode midi.KeySignatureMode, sharpsOrFlats int8) {
-	fmt.Println("KeySignature", key, mode, sharpsOrFlats)
+func (cbk LoggingLexerCallback) KeySignature(key midi.ScaleDegree, mode midi.KeySignatureMode, sharpsOrFlats int8, time uint32) {
+	fmt.Println("KeySignature", key, mode, sharpsOrFlats, time)
+}
+func (cbk LoggingLexerCallback) SMPTEOffset(frameRate midi.SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) {
+	fmt.Println("SMPTEOffset", frameRate, hours, minutes, seconds, frames, fractionalFrames, time)
+}
+func (cbk LoggingLexerCallback) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) {
+	fmt.Println("SequencerSpecific", manufacturerID, data, time)
+}
+func (cbk LoggingLexerCallback) UnknownMeta(metaType uint8, data []byte, time uint32) {
+	fmt.Println("UnknownMeta", metaType, data, time)
 }
 
 func main() {
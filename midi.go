@@ -15,6 +15,8 @@
 package midi
 
 import (
+	"bytes"
+	"context"
 	"io"
 )
 
@@ -35,28 +37,124 @@ const (
 	Done = iota
 )
 
+// Modes of the MidiLexer and Encoder, which can be combined.
+const (
+	// On an error in a track, or a track with no EndOfTrack, report it with ErrorReading()
+	// and carry on from the next chunk. Without this, any error stops the lexer.
+	LenientMode = 1 << iota
+
+	// Enforce the rules of the SMF spec that are otherwise let slide: each track must end with EndOfTrack
+	// exactly at the end of its chunk, the number of tracks must match the header, format 0 files must have
+	// one track, and data bytes must not have the high bit set.
+	StrictMode = 1 << iota
+
+	// For the Encoder. Leave out the status byte of a channel message when it's the same as the one before.
+	RunningStatusMode = 1 << iota
+)
+
 // MidiLexer is a Standard Midi File Lexer.
-// Pass this a ReadSeeker to a MIDI file and a callback that conforms to MidiLexerCallback
+// Pass this a ReadSeeker (or a Reader) to a MIDI file and a callback that conforms to MidiLexerCallback
 // and it'll run over the file, calling events on the callback.
 type MidiLexer struct {
 	callback MidiLexerCallback
 	input    io.ReadSeeker
 
+	// The callback, if it also implements MidiLexerContextCallback, otherwise nil.
+	contextCallback MidiLexerContextCallback
+
+	// The callback, if it also implements MidiLexerChunkCallback, otherwise nil.
+	chunkCallback MidiLexerChunkCallback
+
+	// The callback, if it can ask the lexer to stop (e.g. it came from ErrorCallback), otherwise nil.
+	abortingCallback abortingCallback
+
 	// State of the parser, as per the above constants.
 	state int
 
-	// The location of the next chunk header that we expect to find as an offset from
-	// most recent Chunk header.
+	// Modes, as per the above constants.
+	mode int
+
+	// The location in the file of the next chunk header that we expect to find,
+	// i.e. the end of the current chunk.
 	nextChunkHeader int64
+
+	// The most recent channel message status byte, used for running status.
+	// Zero when there is no running status, e.g. at the start of a track or after a meta or SysEx event.
+	runningStatus uint8
+
+	// When running status is in effect the first data byte has already been read in place of the status byte.
+	pendingData    uint8
+	hasPendingData bool
+
+	// A SysEx message divided into packets has been started but not yet terminated with 0xF7.
+	// While this is set, F7 events are continuation packets rather than escape sequences.
+	inSysEx bool
+
+	// The SMF header.
+	header HeaderData
+
+	// The number of MTrk chunks found so far.
+	tracks int
+
+	// The position of the most recent track event.
+	context EventContext
+
+	// Where the lexer is in the file, for reporting errors.
+	// The offset of the item being lexed, the number of chunks started and index of the event in the current track.
+	// The status byte of the event, if it has been read.
+	offset int64
+	chunks int
+	event  int
+	status uint8
 }
 
 // Construct a new MidiLexer
+// Lexing is quicker if the ReadSeeker is also an io.ByteReader, like bytes.Reader,
+// as is the input of NewMidiLexerFromReader and NewMidiLexerFromBytes.
 func NewMidiLexer(input io.ReadSeeker, callback MidiLexerCallback) *MidiLexer {
-	return &MidiLexer{callback: callback, input: input, state: ExpectHeader}
+	contextCallback, _ := callback.(MidiLexerContextCallback)
+	chunkCallback, _ := callback.(MidiLexerChunkCallback)
+	abortingCallback, _ := callback.(abortingCallback)
+
+	return &MidiLexer{callback: callback, contextCallback: contextCallback, chunkCallback: chunkCallback, abortingCallback: abortingCallback, input: input, state: ExpectHeader}
+}
+
+// SetMode sets the modes of the lexer, e.g. LenientMode. Modes can be combined with |.
+func (lexer *MidiLexer) SetMode(mode int) {
+	lexer.mode = mode
+}
+
+// Construct a new MidiLexer that reads from a Reader that can't seek, such as a pipe or HTTP body.
+// The callbacks are the same as for a ReadSeeker, but in LenientMode a track that overruns its chunk can't be recovered.
+func NewMidiLexerFromReader(input io.Reader, callback MidiLexerCallback) *MidiLexer {
+	var lexer = NewMidiLexer(nil, callback)
+
+	if input != nil {
+		lexer.input = newForwardSeeker(input)
+	}
+
+	return lexer
+}
+
+// Construct a new MidiLexer that reads from MIDI data in memory.
+// This is the quickest way to lex, as reading channel messages doesn't allocate or make system calls.
+func NewMidiLexerFromBytes(data []byte, callback MidiLexerCallback) *MidiLexer {
+	return NewMidiLexer(bytes.NewReader(data), callback)
+}
+
+// Context returns the position of the most recent track event.
+func (lexer *MidiLexer) Context() EventContext {
+	return lexer.context
 }
 
 // Lex starts the MidiLexer running.
 func (lexer *MidiLexer) Lex() error {
+	return lexer.LexContext(context.Background())
+}
+
+// LexContext starts the MidiLexer running, checking between events that the Context isn't done.
+// If it is, the Context's error is returned, wrapped in a LexError.
+func (lexer *MidiLexer) LexContext(ctx context.Context) error {
 	if lexer.callback == nil {
 		return NoCallback
 	}
@@ -69,7 +167,11 @@ func (lexer *MidiLexer) Lex() error {
 	var err error
 
 	for {
-		finished, err = lexer.next()
+		if err = ctx.Err(); err != nil {
+			return lexer.lexError(err)
+		}
+
+		finished, err = lexer.step()
 
 		if err != nil {
 			return err
@@ -79,8 +181,87 @@ func (lexer *MidiLexer) Lex() error {
 			return nil
 		}
 	}
+}
+
+// step lexes the next item like next, but errors are reported to the callback, wrapped in a LexError,
+// and recovered from if in LenientMode.
+func (lexer *MidiLexer) step() (finished bool, err error) {
+	finished, err = lexer.next()
+
+	// If the callback asked to stop, that takes priority over anything else.
+	if abortErr := lexer.abortError(); abortErr != nil {
+		return false, lexer.lexError(abortErr)
+	}
 
-	return nil
+	if err != nil {
+		var lexError = lexer.lexError(err)
+
+		lexer.callback.ErrorReading(lexError)
+
+		if abortErr := lexer.abortError(); abortErr != nil {
+			return false, lexer.lexError(abortErr)
+		}
+
+		// If the error was in a track we know where the next chunk should be, so try to carry on from there.
+		if lexer.mode&LenientMode != 0 && lexer.state == ExpectTrackEvent {
+			_, err = lexer.input.Seek(lexer.nextChunkHeader, 0)
+
+			if err == nil {
+				lexer.state = ExpectChunk
+				return false, nil
+			}
+		}
+
+		return false, lexError
+	}
+
+	return finished, nil
+}
+
+// abortError returns the error from the callback if it has asked the lexer to stop, otherwise nil.
+func (lexer *MidiLexer) abortError() error {
+	if lexer.abortingCallback == nil {
+		return nil
+	}
+
+	return lexer.abortingCallback.abortError()
+}
+
+// parseTrackData parses a variable length value followed by that many bytes of data in a track event.
+// The length comes from the file, so it's checked against what's left of the track's chunk, if that's known,
+// before any data is read.
+func (lexer *MidiLexer) parseTrackData() ([]byte, error) {
+	length, err := parseVarLength(lexer.input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lexer.nextChunkHeader != 0 {
+		var position int64
+		position, err = lexer.input.Seek(0, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if position+int64(length) > lexer.nextChunkHeader {
+			return nil, BadSizeChunk
+		}
+	}
+
+	return readData(lexer.input, length)
+}
+
+// lexError wraps an error with the position of the item being lexed when it happened.
+func (lexer *MidiLexer) lexError(err error) LexError {
+	var lexError = LexError{Err: err, Offset: lexer.offset, Chunk: lexer.chunks - 1, Event: -1, Status: lexer.status}
+
+	if lexer.state == ExpectTrackEvent {
+		lexError.Event = lexer.event
+	}
+
+	return lexError
 }
 
 // next lexes the next item, calling appropriate callbacks.
@@ -100,12 +281,17 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 		return
 	}
 
+	lexer.offset = currentPosition
+	lexer.status = 0
+
 	// See comments for state values above.
 	switch lexer.state {
 	case ExpectHeader:
 		{
 			//fmt.Println("ExpectHeader")
 
+			lexer.chunks++
+
 			var chunkHeader ChunkHeader
 			chunkHeader, err = parseChunkHeader(lexer.input)
 			if chunkHeader.ChunkType != "MThd" {
@@ -123,6 +309,29 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 				return
 			}
 
+			// The header data is 6 bytes long, but the spec allows for it getting longer in future.
+			if chunkHeader.Length != 6 {
+				if lexer.mode&StrictMode != 0 {
+					err = BadSizeChunk
+					return
+				}
+
+				if chunkHeader.Length > 6 {
+					_, err = lexer.input.Seek(int64(chunkHeader.Length)-6, 1)
+
+					if err != nil {
+						return
+					}
+				}
+			}
+
+			if lexer.mode&StrictMode != 0 && header.Format == SingleMultiTrackChannel && header.NumTracks != 1 {
+				err = Format0NotSingleTrack
+				return
+			}
+
+			lexer.header = header
+
 			lexer.callback.Began()
 
 			lexer.callback.Header(header)
@@ -136,6 +345,8 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 		{
 			//fmt.Println("ExpectChunk")
 
+			lexer.chunks++
+
 			var chunkHeader ChunkHeader
 			chunkHeader, err = parseChunkHeader(lexer.input)
 
@@ -145,6 +356,11 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 				// If we expect a chunk and we hit the end of the file, that's not so unexpected after all.
 				// The file has to end some time, and this is the correct boundary upon which to end it.
 				if err == UnexpectedEndOfFile {
+					if lexer.mode&StrictMode != 0 && lexer.tracks != int(lexer.header.NumTracks) {
+						err = WrongNumberOfTracks
+						return
+					}
+
 					lexer.state = Done
 
 					// TODO TEST
@@ -160,11 +376,24 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			}
 
 			lexer.callback.Track(chunkHeader)
-			lexer.nextChunkHeader = int64(chunkHeader.Length) + currentPosition
 
-			// If the header is of an unknown type, skip over it.
+			// The chunk data follows the 8 byte chunk header.
+			lexer.nextChunkHeader = currentPosition + 8 + int64(chunkHeader.Length)
+
+			// If the header is of an unknown type, pass it on if the callback wants it, otherwise skip over it.
 			if chunkHeader.ChunkType != "MTrk" {
-				lexer.input.Seek(lexer.nextChunkHeader, 1)
+				if lexer.chunkCallback != nil && wantsUnknownChunks(lexer.chunkCallback) {
+					var data []byte
+					data, err = parseChunkData(lexer.input, chunkHeader.Length)
+
+					if err != nil {
+						return
+					}
+
+					lexer.chunkCallback.UnknownChunk(chunkHeader, data)
+				} else {
+					lexer.input.Seek(lexer.nextChunkHeader, 0)
+				}
 
 				// Then we expect another chunk.
 				lexer.state = ExpectChunk
@@ -172,6 +401,15 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			} else {
 				// We have a MTrk
 				lexer.state = ExpectTrackEvent
+
+				// Running status and divided SysEx messages don't carry over between tracks.
+				lexer.runningStatus = 0
+				lexer.inSysEx = false
+
+				// Time starts again for each track.
+				lexer.context = EventContext{Track: lexer.tracks}
+				lexer.tracks++
+				lexer.event = -1
 			}
 
 			return
@@ -181,20 +419,21 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 		{
 			//fmt.Println("ExpectTrackEvent")
 
-			// Removed because there is an event to say 'end of chunk'.
-			// TODO: investigate. Could put this back for error cases.
-			// // If we're at the end of the chunk, change the state.
-			// if lexer.nextChunkHeader != 0 {
-
-			// 	// The chunk should end exactly on the chunk boundary, really.
-			// 	if currentPosition == lexer.nextChunkHeader {
-			// 		lexer.state = ExpectChunk
-			// 		return false, nil
-			// 	} else if currentPosition > lexer.nextChunkHeader {
-			// 		//fmt.Println("Chunk end error ", err)
-			// 		return false, BadSizeChunk
-			// 	}
-			// }
+			// Count the event first, so errors about the chunk ending are reported where the event was expected.
+			lexer.event++
+
+			// There is an event to say 'end of chunk', so we shouldn't get to the end of the chunk without it.
+			// In LenientMode check, so that the track can be skipped if it's missing.
+			if lexer.mode&(LenientMode|StrictMode) != 0 && lexer.nextChunkHeader != 0 {
+				if currentPosition == lexer.nextChunkHeader {
+					err = MissingEndOfTrack
+					return
+				} else if currentPosition > lexer.nextChunkHeader {
+					//fmt.Println("Chunk end error ", err)
+					err = BadSizeChunk
+					return
+				}
+			}
 
 			// Time Delta
 			var time uint32
@@ -204,18 +443,55 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 				return
 			}
 
+			lexer.context.DeltaTicks = time
+			lexer.context.AbsoluteTicks += uint64(time)
+			lexer.context.Offset = currentPosition
+
 			// Message type, Message Channel
 			var mType, channel uint8
 			mType, channel, err = readStatusByte(lexer.input)
 
+			if err != nil {
+				return
+			}
+
+			lexer.context.RunningStatus = mType < 0x8
+
+			// This is before the rest of the event is read, which might fail. See MidiLexerContextCallback.
+			if lexer.contextCallback != nil {
+				lexer.contextCallback.BeforeEvent(lexer.context)
+			}
+
 			//fmt.Println("Track Event Type ", mType)
 
+			// A data byte where a status byte was expected means running status:
+			// the status of the previous channel message applies, and this byte is its first data byte.
+			if mType < 0x8 {
+				if lexer.runningStatus == 0 {
+					err = NoRunningStatus
+					return
+				}
+
+				lexer.pendingData = mType<<4 | channel
+				lexer.hasPendingData = true
+
+				mType = lexer.runningStatus >> 4
+				channel = lexer.runningStatus & 0x0F
+			} else if mType == 0xF {
+				// Meta and SysEx events cancel running status.
+				lexer.runningStatus = 0
+			} else {
+				lexer.runningStatus = mType<<4 | channel
+			}
+
+			lexer.status = mType<<4 | channel
+
 			switch mType {
 			// NoteOff
 			case 0x8:
 				{
 					var pitch, velocity uint8
-					pitch, velocity, err = parseTwoUint7(lexer.input)
+					pitch, velocity, err = lexer.parseTwoDataBytes()
 
 					if err != nil {
 						//fmt.Println("NoteOff error ", err)
@@ -229,7 +505,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			case 0x9:
 				{
 					var pitch, velocity uint8
-					pitch, velocity, err = parseTwoUint7(lexer.input)
+					pitch, velocity, err = lexer.parseTwoDataBytes()
 
 					if err != nil {
 						//fmt.Println("NoteOn error ", err)
@@ -243,7 +519,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			case 0xA:
 				{
 					var pitch, pressure uint8
-					pitch, pressure, err = parseTwoUint7(lexer.input)
+					pitch, pressure, err = lexer.parseTwoDataBytes()
 
 					if err != nil {
 						return
@@ -256,7 +532,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			case 0xB:
 				{
 					var controller, value uint8
-					controller, value, err = parseTwoUint7(lexer.input)
+					controller, value, err = lexer.parseTwoDataBytes()
 
 					if err != nil {
 						return
@@ -279,7 +555,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			case 0xC:
 				{
 					var program uint8
-					program, err = parseUint7(lexer.input)
+					program, err = lexer.parseDataByte()
 
 					if err != nil {
 						return
@@ -293,7 +569,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 			case 0xD:
 				{
 					var value uint8
-					value, err = parseUint7(lexer.input)
+					value, err = lexer.parseDataByte()
 
 					if err != nil {
 						return
@@ -308,12 +584,15 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 					// The value is a signed int (relative to centre), and absoluteValue is the actual value in the file.
 					var value int16
 					var absoluteValue uint16
-					value, absoluteValue, err = parsePitchWheelValue(lexer.input)
+					var leastSignificant, mostSignificant uint8
+					leastSignificant, mostSignificant, err = lexer.parseTwoDataBytes()
 
 					if err != nil {
 						return
 					}
 
+					value, absoluteValue = pitchWheelValue(leastSignificant, mostSignificant)
+
 					lexer.callback.PitchWheel(channel, value, absoluteValue, time)
 				}
 
@@ -332,39 +611,36 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 								return
 							}
 
-							//fmt.Println("SystemCommon/RealTime command:", command)
+							//fmt.Println("Meta event command:", command)
+
+							// Every meta event has a length and data, so read them here.
+							// Unknown types can then be passed on without losing our place.
+							var data []byte
+							data, err = lexer.parseTrackData()
+
+							if err != nil {
+								return
+							}
 
-							// TODO: If every one of these takes a length, then take this outside.
-							// Will make for more more robust unknown types.
 							switch command {
 
 							// Sequence number
 							case 0x00:
 								{
-									//fmt.Println("seq no")
-									var length uint8
-									length, err = parseUint8(lexer.input)
-
-									if err != nil {
-										return
-									}
-
 									// Zero length sequences allowed according to http://home.roadrunner.com/~jgglatt/tech/midifile/seq.htm
-									if length == 0 {
+									if len(data) == 0 {
 										lexer.callback.SequenceNumber(channel, 0, false, time)
 
 										return
 									}
 
 									// Otherwise length will be 2 to hold the uint16.
-									var sequenceNumber uint16
-									sequenceNumber, err = parseUint16(lexer.input)
-
-									if err != nil {
+									if len(data) != 2 {
+										err = UnexpectedEventLengthError{"SequenceNumber expected length 0 or 2"}
 										return
 									}
 
-									lexer.callback.SequenceNumber(channel, sequenceNumber, true, time)
+									lexer.callback.SequenceNumber(channel, decodeUint16(data), true, time)
 
 									return
 								}
@@ -372,15 +648,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							// Text event
 							case 0x01:
 								{
-									//fmt.Println("Text")
-									var text string
-									text, err = parseText(lexer.input)
-									//fmt.Println("text value", text, err)
-									if err != nil {
-										return
-									}
-
-									lexer.callback.Text(channel, text, time)
+									lexer.callback.Text(channel, string(data), time)
 
 									return
 								}
@@ -388,15 +656,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							// Copyright text event
 							case 0x02:
 								{
-									//fmt.Println("Copyright")
-									var text string
-									text, err = parseText(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									lexer.callback.CopyrightText(channel, text, time)
+									lexer.callback.CopyrightText(channel, string(data), time)
 
 									return
 								}
@@ -404,46 +664,23 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							// Sequence or track name
 							case 0x03:
 								{
-									var text string
-									text, err = parseText(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									lexer.callback.SequenceName(channel, text, time)
+									lexer.callback.SequenceName(channel, string(data), time)
 
 									return
-
 								}
 
 							// Track instrument name
 							case 0x04:
 								{
-									var text string
-									text, err = parseText(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									lexer.callback.TrackInstrumentName(channel, text, time)
+									lexer.callback.TrackInstrumentName(channel, string(data), time)
 
 									return
-
 								}
 
 							// Lyric text
 							case 0x05:
 								{
-									var text string
-									text, err = parseText(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									lexer.callback.LyricText(channel, text, time)
+									lexer.callback.LyricText(channel, string(data), time)
 
 									return
 								}
@@ -451,14 +688,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							// Marker text
 							case 0x06:
 								{
-									var text string
-									text, err = parseText(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									lexer.callback.MarkerText(channel, text, time)
+									lexer.callback.MarkerText(channel, string(data), time)
 
 									return
 								}
@@ -466,14 +696,7 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							// Cue point text
 							case 0x07:
 								{
-									var text string
-									text, err = parseText(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									lexer.callback.CuePointText(channel, text, time)
+									lexer.callback.CuePointText(channel, string(data), time)
 
 									return
 								}
@@ -481,66 +704,52 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							case 0x20:
 								{
 									// Obsolete 'MIDI Channel'
-									//fmt.Println("MIDI Channel obsolete")
-									var length uint32
-									length, err = parseVarLength(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									if length != 1 {
+									// The data is the channel value.
+									if len(data) != 1 {
 										err = UnexpectedEventLengthError{"Midi Channel Event expected length 1"}
 										return
 									}
 
-									// This is the channel value.
-									// Just forget this one.
-									_, err = parseUint8(lexer.input)
-
-									if err != nil {
-										return
-									}
+									lexer.callback.ChannelPrefix(data[0], time)
 								}
 
 							case 0x21:
 								{
 									// Obsolete 'MIDI Port'
-									//fmt.Println("MIDI PORT obsolete")
-									var length uint32
-									length, err = parseVarLength(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									if length != 1 {
+									// The data is the port value.
+									if len(data) != 1 {
 										err = UnexpectedEventLengthError{"MIDI Port Event expected length 1"}
 										return
 									}
 
-									// This is the port value.
-									// Just forget this one.
-									_, err = parseUint8(lexer.input)
-
-									if err != nil {
-										return
-									}
+									lexer.callback.MidiPort(data[0], time)
 								}
 
 							// End of track
 							case 0x2F:
 								{
-									var length uint32
-									length, err = parseVarLength(lexer.input)
-
-									if err != nil {
+									if len(data) != 0 {
+										err = UnexpectedEventLengthError{"EndOfTrack expected length 0"}
 										return
 									}
 
-									if length != 0 {
-										err = UnexpectedEventLengthError{"EndOfTrack expected length 0"}
-										return
+									// The track should end exactly at the end of the chunk.
+									if lexer.mode&StrictMode != 0 && lexer.nextChunkHeader != 0 {
+										var endPosition int64
+										endPosition, err = lexer.input.Seek(0, 1)
+
+										if err != nil {
+											return
+										}
+
+										// More events after the end of the track, or the end of the track after the end of the chunk.
+										if endPosition < lexer.nextChunkHeader {
+											err = EndOfTrackNotLast
+											return
+										} else if endPosition > lexer.nextChunkHeader {
+											err = BadSizeChunk
+											return
+										}
 									}
 
 									lexer.callback.EndOfTrack(channel, time)
@@ -548,30 +757,27 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 									// Expect the next chunk event.
 									lexer.state = ExpectChunk
 
-									return false, nil
+									// In LenientMode skip anything left in the chunk after the end of the track.
+									if lexer.mode&LenientMode != 0 && lexer.nextChunkHeader > currentPosition {
+										_, err = lexer.input.Seek(lexer.nextChunkHeader, 0)
+									}
+
+									return
 								}
 
 							// Set tempo
 							case 0x51:
 								{
-									// TODO TEST
-
-									var length uint32
-									length, err = parseVarLength(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									if length != 3 {
+									if len(data) != 3 {
 										err = UnexpectedEventLengthError{"Tempo expected length 3"}
 										return
 									}
 
-									var microsecondsPerCrotchet uint32
-									microsecondsPerCrotchet, err = parseUint24(lexer.input)
+									var microsecondsPerCrotchet uint32 = decodeUint24(data)
 
-									if err != nil {
+									// A tempo of zero would be a division by zero.
+									if microsecondsPerCrotchet == 0 {
+										err = InvalidTempo
 										return
 									}
 
@@ -582,49 +788,33 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 									lexer.callback.Tempo(bpm, microsecondsPerCrotchet, time)
 								}
 
-							// Time signature
-							case 0x58:
+							// SMPTE Offset
+							case 0x54:
 								{
-									var length uint32
-									length, err = parseVarLength(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									if length != 4 {
-										err = UnexpectedEventLengthError{"TimeSignature expected length 4"}
+									if len(data) != 5 {
+										err = UnexpectedEventLengthError{"SMPTEOffset expected length 5"}
 										return
 									}
 
-									// TODO TEST
-									var numerator uint8
-									numerator, err = parseUint8(lexer.input)
+									// The hours byte is 0rrhhhhh, where rr is the frame rate.
+									var frameRate SMPTEFrameRate = smpteOffsetFrameRates[(data[0]>>5)&0x03]
+									var hours uint8 = data[0] & 0x1F
 
-									if err != nil {
-										return
-									}
-
-									var denomenator uint8
-									denomenator, err = parseUint8(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									var clocksPerClick uint8
-									clocksPerClick, err = parseUint8(lexer.input)
+									lexer.callback.SMPTEOffset(frameRate, hours, data[1], data[2], data[3], data[4], time)
+								}
 
-									if err != nil {
+							// Time signature
+							case 0x58:
+								{
+									if len(data) != 4 {
+										err = UnexpectedEventLengthError{"TimeSignature expected length 4"}
 										return
 									}
 
-									var demiSemiQuaverPerQuarter uint8
-									demiSemiQuaverPerQuarter, err = parseUint8(lexer.input)
-
-									if err != nil {
-										return
-									}
+									var numerator uint8 = data[0]
+									var denomenator uint8 = data[1]
+									var clocksPerClick uint8 = data[2]
+									var demiSemiQuaverPerQuarter uint8 = data[3]
 
 									//fmt.Println("TimeSignature event", numerator, denomenator, clocksPerClick, demiSemiQuaverPerQuarter, time)
 
@@ -636,106 +826,98 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 							// Key signature
 							case 0x59:
 								{
-									// TODO TEST
-									var length uint32
-									var sharpsOrFlats int8
-									var mode uint8
-
-									length, err = parseVarLength(lexer.input)
-
-									if err != nil {
-										return
-									}
-
-									if length != 2 {
+									if len(data) != 2 {
 										err = UnexpectedEventLengthError{"KeySignature expected length 2"}
 										return
 									}
 
 									// Signed int, positive is sharps, negative is flats.
-									sharpsOrFlats, err = parseInt8(lexer.input)
-
-									if err != nil {
-										return
-									}
+									var sharpsOrFlats int8 = int8(data[0])
 
 									// Mode is Major or Minor.
-									mode, err = parseUint8(lexer.input)
-
-									if err != nil {
-										return
-									}
+									var mode uint8 = data[1]
 
 									key, resultMode := keySignatureFromSharpsOrFlats(sharpsOrFlats, mode)
 
-									lexer.callback.KeySignature(key, resultMode, sharpsOrFlats)
+									lexer.callback.KeySignature(key, resultMode, sharpsOrFlats, time)
 								}
 
 							// Sequencer specific info
 							case 0x7F:
 								{
-									//fmt.Println("0x7F")
-								}
+									// The manufacturer ID is one byte, or three if the first is zero.
+									var idLength = 1
+									if len(data) > 0 && data[0] == 0x00 {
+										idLength = 3
+									}
 
-							// Timing clock
-							case 0xF8:
-								{
-									//fmt.Println("0xF8")
-								}
+									// Too short for a manufacturer ID. Pass it on as it is, rather than lose it.
+									if len(data) < idLength {
+										if lexer.mode&StrictMode != 0 {
+											err = UnexpectedEventLengthError{"SequencerSpecific too short for manufacturer ID"}
+											return
+										}
 
-							// Start current sequence
-							case 0xFA:
-								{
-									//fmt.Println("0xFA")
-								}
+										lexer.callback.UnknownMeta(command, data, time)
+										break
+									}
 
-							// Continue stopped sequence where left off
-							case 0xFB:
-								{
-									//fmt.Println("0xFB")
+									lexer.callback.SequencerSpecific(data[:idLength], data[idLength:], time)
 								}
 
-							// Stop sequence
-							case 0xFc:
-								{
-									//fmt.Println("0xFc")
-								}
 							default:
 								//fmt.Println("Unrecognised meta command", command)
+								lexer.callback.UnknownMeta(command, data, time)
 							}
 
 						}
 
-					default:
-						//fmt.Println("Unrecognised message type", mType)
-					}
+					// System Exclusive
+					case 0x0:
+						{
+							var data []byte
+							data, err = lexer.parseTrackData()
 
-					//
-				}
+							if err != nil {
+								return
+							}
 
-				// This covers all cases.
+							// A SysEx message that doesn't end with 0xF7 is continued in subsequent F7 packets.
+							lexer.inSysEx = !endsSysEx(data)
 
-			// Now we need to see if we're at the end of a Track Data chunk.
-			default:
-				{
-					var length uint32
-					length, err = parseVarLength(lexer.input)
+							lexer.callback.SysEx(data, time)
 
-					if err != nil {
-						return
-					}
+							return
+						}
+
+					// SysEx continuation packet or escape sequence
+					case 0x7:
+						{
+							var data []byte
+							data, err = lexer.parseTrackData()
 
-					//fmt.Println("Type Unrecognised", mType, "length", length)
+							if err != nil {
+								return
+							}
 
-					// Read length of chunk
-					for i := uint32(0); i < length; i++ {
-						_, err = parseUint8(lexer.input)
+							if lexer.inSysEx {
+								lexer.inSysEx = !endsSysEx(data)
+
+								lexer.callback.SysExContinuation(data, time)
+							} else {
+								lexer.callback.EscapeSequence(data, time)
+							}
 
-						if err != nil {
 							return
 						}
+
+					default:
+						//fmt.Println("Unrecognised message type", mType)
 					}
+
+					//
 				}
+
 			}
 
 		}
@@ -751,3 +933,51 @@ func (lexer *MidiLexer) next() (finished bool, err error) {
 
 	return
 }
+
+// parseDataByte reads the next 7-bit data byte of a channel message.
+// If running status is in effect, the first data byte has already been read and is returned instead.
+func (lexer *MidiLexer) parseDataByte() (uint8, error) {
+	if lexer.hasPendingData {
+		lexer.hasPendingData = false
+		return lexer.pendingData & 0x7f, nil
+	}
+
+	if lexer.mode&StrictMode == 0 {
+		return parseUint7(lexer.input)
+	}
+
+	// In StrictMode the high bit must be clear, rather than ignored.
+	value, err := parseUint8(lexer.input)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if value&0x80 != 0 {
+		return 0, BadDataByte
+	}
+
+	return value, nil
+}
+
+// parseTwoDataBytes reads the two 7-bit data bytes of a channel message, taking account of running status.
+func (lexer *MidiLexer) parseTwoDataBytes() (uint8, uint8, error) {
+	if !lexer.hasPendingData && lexer.mode&StrictMode == 0 {
+		return parseTwoUint7(lexer.input)
+	}
+
+	first, err := lexer.parseDataByte()
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	second, err := lexer.parseDataByte()
+
+	return first, second, err
+}
+
+// endsSysEx returns true if the SysEx packet data is terminated with 0xF7, i.e. this is the last packet.
+func endsSysEx(data []byte) bool {
+	return len(data) > 0 && data[len(data)-1] == 0xF7
+}
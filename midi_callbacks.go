@@ -0,0 +1,873 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Callback implementations.
+ * Ready-made implementations of MidiLexerCallback, so you only need to write the methods you're interested in.
+ */
+
+package midi
+
+// BaseLexerCallback implements MidiLexerCallback and MidiLexerContextCallback by doing nothing.
+// Embed it in your own callback and write only the methods you need.
+// Methods added to MidiLexerCallback in future will be added here too, so your callback won't break.
+type BaseLexerCallback struct{}
+
+func (BaseLexerCallback) Began()                                                          {}
+func (BaseLexerCallback) Finished()                                                       {}
+func (BaseLexerCallback) ErrorReading(err error)                                          {}
+func (BaseLexerCallback) ErrorOpeningFile()                                               {}
+func (BaseLexerCallback) Header(header HeaderData)                                        {}
+func (BaseLexerCallback) Track(header ChunkHeader)                                        {}
+func (BaseLexerCallback) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) {}
+func (BaseLexerCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32)  {}
+func (BaseLexerCallback) PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) {
+}
+func (BaseLexerCallback) ControlChange(channel uint8, controller uint8, value uint8, time uint32) {}
+func (BaseLexerCallback) ProgramChange(channel uint8, program uint8, time uint32)                 {}
+func (BaseLexerCallback) ChannelAfterTouch(channel uint8, value uint8, time uint32)               {}
+func (BaseLexerCallback) PitchWheel(channel uint8, value int16, absValue uint16, time uint32)     {}
+func (BaseLexerCallback) TimeCodeQuarter(messageType uint8, values uint8, time uint32)            {}
+func (BaseLexerCallback) SongPositionPointer(beats uint16, time uint32)                           {}
+func (BaseLexerCallback) SongSelect(song uint8, time uint32)                                      {}
+func (BaseLexerCallback) Undefined1(time uint32)                                                  {}
+func (BaseLexerCallback) Undefined2(time uint32)                                                  {}
+func (BaseLexerCallback) TuneRequest(time uint32)                                                 {}
+func (BaseLexerCallback) TimingClock(time uint32)                                                 {}
+func (BaseLexerCallback) Undefined3(time uint32)                                                  {}
+func (BaseLexerCallback) Start(time uint32)                                                       {}
+func (BaseLexerCallback) Continue(time uint32)                                                    {}
+func (BaseLexerCallback) Stop(time uint32)                                                        {}
+func (BaseLexerCallback) Undefined4(time uint32)                                                  {}
+func (BaseLexerCallback) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32)           {}
+func (BaseLexerCallback) ActiveSensing(time uint32)                                               {}
+func (BaseLexerCallback) Reset(time uint32)                                                       {}
+func (BaseLexerCallback) Done(time uint32)                                                        {}
+func (BaseLexerCallback) SysEx(data []byte, time uint32)                                          {}
+func (BaseLexerCallback) SysExContinuation(data []byte, time uint32)                              {}
+func (BaseLexerCallback) EscapeSequence(data []byte, time uint32)                                 {}
+func (BaseLexerCallback) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
+}
+func (BaseLexerCallback) Text(channel uint8, text string, time uint32) {}
+func (BaseLexerCallback) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) {
+}
+func (BaseLexerCallback) CopyrightText(channel uint8, text string, time uint32)       {}
+func (BaseLexerCallback) SequenceName(channel uint8, text string, time uint32)        {}
+func (BaseLexerCallback) TrackInstrumentName(channel uint8, text string, time uint32) {}
+func (BaseLexerCallback) LyricText(channel uint8, text string, time uint32)           {}
+func (BaseLexerCallback) MarkerText(channel uint8, text string, time uint32)          {}
+func (BaseLexerCallback) CuePointText(channel uint8, text string, time uint32)        {}
+func (BaseLexerCallback) EndOfTrack(channel uint8, time uint32)                       {}
+func (BaseLexerCallback) ChannelPrefix(channel uint8, time uint32)                    {}
+func (BaseLexerCallback) MidiPort(port uint8, time uint32)                            {}
+func (BaseLexerCallback) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
+}
+func (BaseLexerCallback) SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) {
+}
+func (BaseLexerCallback) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) {}
+func (BaseLexerCallback) UnknownMeta(metaType uint8, data []byte, time uint32)              {}
+func (BaseLexerCallback) BeforeEvent(context EventContext)                                  {}
+
+// FuncLexerCallback implements MidiLexerCallback, MidiLexerContextCallback and MidiLexerChunkCallback by calling the function for each method, if it is set.
+// Unknown chunks are only read if OnUnknownChunk is set.
+// e.g. &FuncLexerCallback{OnNoteOn: func(channel uint8, pitch uint8, velocity uint8, time uint32) { ... }}
+type FuncLexerCallback struct {
+	OnBegan                func()
+	OnFinished             func()
+	OnErrorReading         func(err error)
+	OnErrorOpeningFile     func()
+	OnHeader               func(header HeaderData)
+	OnTrack                func(header ChunkHeader)
+	OnNoteOff              func(channel uint8, pitch uint8, velocity uint8, time uint32)
+	OnNoteOn               func(channel uint8, pitch uint8, velocity uint8, time uint32)
+	OnPolyphonicAfterTouch func(channel uint8, pitch uint8, pressure uint8, time uint32)
+	OnControlChange        func(channel uint8, controller uint8, value uint8, time uint32)
+	OnProgramChange        func(channel uint8, program uint8, time uint32)
+	OnChannelAfterTouch    func(channel uint8, value uint8, time uint32)
+	OnPitchWheel           func(channel uint8, value int16, absValue uint16, time uint32)
+	OnTimeCodeQuarter      func(messageType uint8, values uint8, time uint32)
+	OnSongPositionPointer  func(beats uint16, time uint32)
+	OnSongSelect           func(song uint8, time uint32)
+	OnUndefined1           func(time uint32)
+	OnUndefined2           func(time uint32)
+	OnTuneRequest          func(time uint32)
+	OnTimingClock          func(time uint32)
+	OnUndefined3           func(time uint32)
+	OnStart                func(time uint32)
+	OnContinue             func(time uint32)
+	OnStop                 func(time uint32)
+	OnUndefined4           func(time uint32)
+	OnTempo                func(bpm uint32, microsecondsPerCrotchet uint32, time uint32)
+	OnActiveSensing        func(time uint32)
+	OnReset                func(time uint32)
+	OnDone                 func(time uint32)
+	OnSysEx                func(data []byte, time uint32)
+	OnSysExContinuation    func(data []byte, time uint32)
+	OnEscapeSequence       func(data []byte, time uint32)
+	OnSequenceNumber       func(channel uint8, number uint16, numberGiven bool, time uint32)
+	OnText                 func(channel uint8, text string, time uint32)
+	OnKeySignature         func(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32)
+	OnCopyrightText        func(channel uint8, text string, time uint32)
+	OnSequenceName         func(channel uint8, text string, time uint32)
+	OnTrackInstrumentName  func(channel uint8, text string, time uint32)
+	OnLyricText            func(channel uint8, text string, time uint32)
+	OnMarkerText           func(channel uint8, text string, time uint32)
+	OnCuePointText         func(channel uint8, text string, time uint32)
+	OnEndOfTrack           func(channel uint8, time uint32)
+	OnChannelPrefix        func(channel uint8, time uint32)
+	OnMidiPort             func(port uint8, time uint32)
+	OnTimeSignature        func(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32)
+	OnSMPTEOffset          func(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32)
+	OnSequencerSpecific    func(manufacturerID []byte, data []byte, time uint32)
+	OnUnknownMeta          func(metaType uint8, data []byte, time uint32)
+	OnBeforeEvent          func(context EventContext)
+	OnUnknownChunk         func(header ChunkHeader, data []byte)
+}
+
+func (cbk *FuncLexerCallback) Began() {
+	if cbk.OnBegan != nil {
+		cbk.OnBegan()
+	}
+}
+
+func (cbk *FuncLexerCallback) Finished() {
+	if cbk.OnFinished != nil {
+		cbk.OnFinished()
+	}
+}
+
+func (cbk *FuncLexerCallback) ErrorReading(err error) {
+	if cbk.OnErrorReading != nil {
+		cbk.OnErrorReading(err)
+	}
+}
+
+func (cbk *FuncLexerCallback) ErrorOpeningFile() {
+	if cbk.OnErrorOpeningFile != nil {
+		cbk.OnErrorOpeningFile()
+	}
+}
+
+func (cbk *FuncLexerCallback) Header(header HeaderData) {
+	if cbk.OnHeader != nil {
+		cbk.OnHeader(header)
+	}
+}
+
+func (cbk *FuncLexerCallback) Track(header ChunkHeader) {
+	if cbk.OnTrack != nil {
+		cbk.OnTrack(header)
+	}
+}
+
+func (cbk *FuncLexerCallback) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	if cbk.OnNoteOff != nil {
+		cbk.OnNoteOff(channel, pitch, velocity, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	if cbk.OnNoteOn != nil {
+		cbk.OnNoteOn(channel, pitch, velocity, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) {
+	if cbk.OnPolyphonicAfterTouch != nil {
+		cbk.OnPolyphonicAfterTouch(channel, pitch, pressure, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) ControlChange(channel uint8, controller uint8, value uint8, time uint32) {
+	if cbk.OnControlChange != nil {
+		cbk.OnControlChange(channel, controller, value, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) ProgramChange(channel uint8, program uint8, time uint32) {
+	if cbk.OnProgramChange != nil {
+		cbk.OnProgramChange(channel, program, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) ChannelAfterTouch(channel uint8, value uint8, time uint32) {
+	if cbk.OnChannelAfterTouch != nil {
+		cbk.OnChannelAfterTouch(channel, value, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) PitchWheel(channel uint8, value int16, absValue uint16, time uint32) {
+	if cbk.OnPitchWheel != nil {
+		cbk.OnPitchWheel(channel, value, absValue, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) TimeCodeQuarter(messageType uint8, values uint8, time uint32) {
+	if cbk.OnTimeCodeQuarter != nil {
+		cbk.OnTimeCodeQuarter(messageType, values, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SongPositionPointer(beats uint16, time uint32) {
+	if cbk.OnSongPositionPointer != nil {
+		cbk.OnSongPositionPointer(beats, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SongSelect(song uint8, time uint32) {
+	if cbk.OnSongSelect != nil {
+		cbk.OnSongSelect(song, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Undefined1(time uint32) {
+	if cbk.OnUndefined1 != nil {
+		cbk.OnUndefined1(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Undefined2(time uint32) {
+	if cbk.OnUndefined2 != nil {
+		cbk.OnUndefined2(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) TuneRequest(time uint32) {
+	if cbk.OnTuneRequest != nil {
+		cbk.OnTuneRequest(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) TimingClock(time uint32) {
+	if cbk.OnTimingClock != nil {
+		cbk.OnTimingClock(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Undefined3(time uint32) {
+	if cbk.OnUndefined3 != nil {
+		cbk.OnUndefined3(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Start(time uint32) {
+	if cbk.OnStart != nil {
+		cbk.OnStart(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Continue(time uint32) {
+	if cbk.OnContinue != nil {
+		cbk.OnContinue(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Stop(time uint32) {
+	if cbk.OnStop != nil {
+		cbk.OnStop(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Undefined4(time uint32) {
+	if cbk.OnUndefined4 != nil {
+		cbk.OnUndefined4(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32) {
+	if cbk.OnTempo != nil {
+		cbk.OnTempo(bpm, microsecondsPerCrotchet, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) ActiveSensing(time uint32) {
+	if cbk.OnActiveSensing != nil {
+		cbk.OnActiveSensing(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Reset(time uint32) {
+	if cbk.OnReset != nil {
+		cbk.OnReset(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Done(time uint32) {
+	if cbk.OnDone != nil {
+		cbk.OnDone(time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SysEx(data []byte, time uint32) {
+	if cbk.OnSysEx != nil {
+		cbk.OnSysEx(data, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SysExContinuation(data []byte, time uint32) {
+	if cbk.OnSysExContinuation != nil {
+		cbk.OnSysExContinuation(data, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) EscapeSequence(data []byte, time uint32) {
+	if cbk.OnEscapeSequence != nil {
+		cbk.OnEscapeSequence(data, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
+	if cbk.OnSequenceNumber != nil {
+		cbk.OnSequenceNumber(channel, number, numberGiven, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) Text(channel uint8, text string, time uint32) {
+	if cbk.OnText != nil {
+		cbk.OnText(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) {
+	if cbk.OnKeySignature != nil {
+		cbk.OnKeySignature(key, mode, sharpsOrFlats, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) CopyrightText(channel uint8, text string, time uint32) {
+	if cbk.OnCopyrightText != nil {
+		cbk.OnCopyrightText(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SequenceName(channel uint8, text string, time uint32) {
+	if cbk.OnSequenceName != nil {
+		cbk.OnSequenceName(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) TrackInstrumentName(channel uint8, text string, time uint32) {
+	if cbk.OnTrackInstrumentName != nil {
+		cbk.OnTrackInstrumentName(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) LyricText(channel uint8, text string, time uint32) {
+	if cbk.OnLyricText != nil {
+		cbk.OnLyricText(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) MarkerText(channel uint8, text string, time uint32) {
+	if cbk.OnMarkerText != nil {
+		cbk.OnMarkerText(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) CuePointText(channel uint8, text string, time uint32) {
+	if cbk.OnCuePointText != nil {
+		cbk.OnCuePointText(channel, text, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) EndOfTrack(channel uint8, time uint32) {
+	if cbk.OnEndOfTrack != nil {
+		cbk.OnEndOfTrack(channel, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) ChannelPrefix(channel uint8, time uint32) {
+	if cbk.OnChannelPrefix != nil {
+		cbk.OnChannelPrefix(channel, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) MidiPort(port uint8, time uint32) {
+	if cbk.OnMidiPort != nil {
+		cbk.OnMidiPort(port, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
+	if cbk.OnTimeSignature != nil {
+		cbk.OnTimeSignature(numerator, denomenator, clocksPerClick, demiSemiQuaverPerQuarter, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) {
+	if cbk.OnSMPTEOffset != nil {
+		cbk.OnSMPTEOffset(frameRate, hours, minutes, seconds, frames, fractionalFrames, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) {
+	if cbk.OnSequencerSpecific != nil {
+		cbk.OnSequencerSpecific(manufacturerID, data, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) UnknownMeta(metaType uint8, data []byte, time uint32) {
+	if cbk.OnUnknownMeta != nil {
+		cbk.OnUnknownMeta(metaType, data, time)
+	}
+}
+
+func (cbk *FuncLexerCallback) BeforeEvent(context EventContext) {
+	if cbk.OnBeforeEvent != nil {
+		cbk.OnBeforeEvent(context)
+	}
+}
+
+func (cbk *FuncLexerCallback) UnknownChunk(header ChunkHeader, data []byte) {
+	if cbk.OnUnknownChunk != nil {
+		cbk.OnUnknownChunk(header, data)
+	}
+}
+
+func (cbk *FuncLexerCallback) wantsUnknownChunks() bool {
+	return cbk.OnUnknownChunk != nil
+}
+
+// BaseLexerErrorCallback implements MidiLexerErrorCallback and MidiLexerErrorContextCallback by doing nothing and returning nil.
+// Embed it in your own callback and write only the methods you need.
+type BaseLexerErrorCallback struct{}
+
+func (BaseLexerErrorCallback) Began() error                   { return nil }
+func (BaseLexerErrorCallback) Finished() error                { return nil }
+func (BaseLexerErrorCallback) ErrorReading(err error) error   { return nil }
+func (BaseLexerErrorCallback) ErrorOpeningFile() error        { return nil }
+func (BaseLexerErrorCallback) Header(header HeaderData) error { return nil }
+func (BaseLexerErrorCallback) Track(header ChunkHeader) error { return nil }
+func (BaseLexerErrorCallback) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) ControlChange(channel uint8, controller uint8, value uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) ProgramChange(channel uint8, program uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) ChannelAfterTouch(channel uint8, value uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) PitchWheel(channel uint8, value int16, absValue uint16, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) TimeCodeQuarter(messageType uint8, values uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) SongPositionPointer(beats uint16, time uint32) error { return nil }
+func (BaseLexerErrorCallback) SongSelect(song uint8, time uint32) error            { return nil }
+func (BaseLexerErrorCallback) Undefined1(time uint32) error                        { return nil }
+func (BaseLexerErrorCallback) Undefined2(time uint32) error                        { return nil }
+func (BaseLexerErrorCallback) TuneRequest(time uint32) error                       { return nil }
+func (BaseLexerErrorCallback) TimingClock(time uint32) error                       { return nil }
+func (BaseLexerErrorCallback) Undefined3(time uint32) error                        { return nil }
+func (BaseLexerErrorCallback) Start(time uint32) error                             { return nil }
+func (BaseLexerErrorCallback) Continue(time uint32) error                          { return nil }
+func (BaseLexerErrorCallback) Stop(time uint32) error                              { return nil }
+func (BaseLexerErrorCallback) Undefined4(time uint32) error                        { return nil }
+func (BaseLexerErrorCallback) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) ActiveSensing(time uint32) error                  { return nil }
+func (BaseLexerErrorCallback) Reset(time uint32) error                          { return nil }
+func (BaseLexerErrorCallback) Done(time uint32) error                           { return nil }
+func (BaseLexerErrorCallback) SysEx(data []byte, time uint32) error             { return nil }
+func (BaseLexerErrorCallback) SysExContinuation(data []byte, time uint32) error { return nil }
+func (BaseLexerErrorCallback) EscapeSequence(data []byte, time uint32) error    { return nil }
+func (BaseLexerErrorCallback) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) Text(channel uint8, text string, time uint32) error { return nil }
+func (BaseLexerErrorCallback) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) CopyrightText(channel uint8, text string, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) SequenceName(channel uint8, text string, time uint32) error { return nil }
+func (BaseLexerErrorCallback) TrackInstrumentName(channel uint8, text string, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) LyricText(channel uint8, text string, time uint32) error    { return nil }
+func (BaseLexerErrorCallback) MarkerText(channel uint8, text string, time uint32) error   { return nil }
+func (BaseLexerErrorCallback) CuePointText(channel uint8, text string, time uint32) error { return nil }
+func (BaseLexerErrorCallback) EndOfTrack(channel uint8, time uint32) error                { return nil }
+func (BaseLexerErrorCallback) ChannelPrefix(channel uint8, time uint32) error             { return nil }
+func (BaseLexerErrorCallback) MidiPort(port uint8, time uint32) error                     { return nil }
+func (BaseLexerErrorCallback) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) error {
+	return nil
+}
+func (BaseLexerErrorCallback) UnknownMeta(metaType uint8, data []byte, time uint32) error { return nil }
+func (BaseLexerErrorCallback) BeforeEvent(context EventContext) error                     { return nil }
+
+// ErrorCallback adapts a MidiLexerErrorCallback so it can be passed to a MidiLexer.
+// Once a method returns an error no more methods are called, and the lexer stops.
+func ErrorCallback(callback MidiLexerErrorCallback) MidiLexerCallback {
+	var adapter = &errorCallbackAdapter{callback: callback}
+	adapter.contextCallback, _ = callback.(MidiLexerErrorContextCallback)
+
+	// Only implement MidiLexerChunkCallback if the callback does, so that otherwise unknown chunks aren't read.
+	if chunkCallback, ok := callback.(MidiLexerErrorChunkCallback); ok {
+		return &errorChunkCallbackAdapter{errorCallbackAdapter: adapter, chunkCallback: chunkCallback}
+	}
+
+	return adapter
+}
+
+// optionalChunkCallback is a MidiLexerChunkCallback that might not want unknown chunks after all.
+type optionalChunkCallback interface {
+	// wantsUnknownChunks returns whether to read unknown chunks and pass them to UnknownChunk.
+	wantsUnknownChunks() bool
+}
+
+// wantsUnknownChunks returns whether a MidiLexerChunkCallback wants unknown chunks.
+func wantsUnknownChunks(callback MidiLexerChunkCallback) bool {
+	var optional, ok = callback.(optionalChunkCallback)
+
+	return !ok || optional.wantsUnknownChunks()
+}
+
+// abortingCallback is a MidiLexerCallback that can ask the lexer to stop.
+type abortingCallback interface {
+	// abortError returns the reason to stop, or nil to carry on.
+	abortError() error
+}
+
+// errorCallbackAdapter is the MidiLexerCallback returned by ErrorCallback.
+type errorCallbackAdapter struct {
+	callback        MidiLexerErrorCallback
+	contextCallback MidiLexerErrorContextCallback
+
+	// The first error returned by the callback.
+	err error
+}
+
+func (cbk *errorCallbackAdapter) abortError() error {
+	return cbk.err
+}
+
+// errorChunkCallbackAdapter is the MidiLexerCallback returned by ErrorCallback for a MidiLexerErrorChunkCallback.
+type errorChunkCallbackAdapter struct {
+	*errorCallbackAdapter
+	chunkCallback MidiLexerErrorChunkCallback
+}
+
+func (cbk *errorChunkCallbackAdapter) UnknownChunk(header ChunkHeader, data []byte) {
+	if cbk.err == nil {
+		cbk.err = cbk.chunkCallback.UnknownChunk(header, data)
+	}
+}
+
+func (cbk *errorCallbackAdapter) BeforeEvent(context EventContext) {
+	if cbk.err == nil && cbk.contextCallback != nil {
+		cbk.err = cbk.contextCallback.BeforeEvent(context)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Began() {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Began()
+	}
+}
+
+func (cbk *errorCallbackAdapter) Finished() {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Finished()
+	}
+}
+
+func (cbk *errorCallbackAdapter) ErrorReading(err error) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ErrorReading(err)
+	}
+}
+
+func (cbk *errorCallbackAdapter) ErrorOpeningFile() {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ErrorOpeningFile()
+	}
+}
+
+func (cbk *errorCallbackAdapter) Header(header HeaderData) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Header(header)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Track(header ChunkHeader) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Track(header)
+	}
+}
+
+func (cbk *errorCallbackAdapter) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.NoteOff(channel, pitch, velocity, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.NoteOn(channel, pitch, velocity, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.PolyphonicAfterTouch(channel, pitch, pressure, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) ControlChange(channel uint8, controller uint8, value uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ControlChange(channel, controller, value, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) ProgramChange(channel uint8, program uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ProgramChange(channel, program, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) ChannelAfterTouch(channel uint8, value uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ChannelAfterTouch(channel, value, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) PitchWheel(channel uint8, value int16, absValue uint16, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.PitchWheel(channel, value, absValue, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) TimeCodeQuarter(messageType uint8, values uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.TimeCodeQuarter(messageType, values, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SongPositionPointer(beats uint16, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SongPositionPointer(beats, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SongSelect(song uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SongSelect(song, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Undefined1(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Undefined1(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Undefined2(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Undefined2(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) TuneRequest(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.TuneRequest(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) TimingClock(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.TimingClock(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Undefined3(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Undefined3(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Start(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Start(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Continue(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Continue(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Stop(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Stop(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Undefined4(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Undefined4(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Tempo(bpm, microsecondsPerCrotchet, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) ActiveSensing(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ActiveSensing(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Reset(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Reset(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Done(time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Done(time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SysEx(data []byte, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SysEx(data, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SysExContinuation(data []byte, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SysExContinuation(data, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) EscapeSequence(data []byte, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.EscapeSequence(data, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SequenceNumber(channel, number, numberGiven, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) Text(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.Text(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.KeySignature(key, mode, sharpsOrFlats, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) CopyrightText(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.CopyrightText(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SequenceName(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SequenceName(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) TrackInstrumentName(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.TrackInstrumentName(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) LyricText(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.LyricText(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) MarkerText(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.MarkerText(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) CuePointText(channel uint8, text string, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.CuePointText(channel, text, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) EndOfTrack(channel uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.EndOfTrack(channel, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) ChannelPrefix(channel uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.ChannelPrefix(channel, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) MidiPort(port uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.MidiPort(port, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.TimeSignature(numerator, denomenator, clocksPerClick, demiSemiQuaverPerQuarter, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SMPTEOffset(frameRate, hours, minutes, seconds, frames, fractionalFrames, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.SequencerSpecific(manufacturerID, data, time)
+	}
+}
+
+func (cbk *errorCallbackAdapter) UnknownMeta(metaType uint8, data []byte, time uint32) {
+	if cbk.err == nil {
+		cbk.err = cbk.callback.UnknownMeta(metaType, data, time)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the callback implementations.
+ */
+
+package midi
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// A callback that only implements the methods it needs.
+type noteCountingCallback struct {
+	BaseLexerCallback
+	notes int
+}
+
+func (cbk *noteCountingCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	cbk.notes++
+}
+
+// Embedding BaseLexerCallback should be enough to satisfy the interfaces.
+func TestBaseLexerCallback(t *testing.T) {
+	var callback = new(noteCountingCallback)
+
+	var _ MidiLexerCallback = callback
+	var _ MidiLexerContextCallback = callback
+
+	assertNoError(NewMidiLexerFromBytes(testMidiFile(), callback).Lex(), t)
+	assertIntsEqual(callback.notes, 3, t)
+}
+
+// FuncLexerCallback should call the functions that are set.
+func TestFuncLexerCallback(t *testing.T) {
+	var notes, tempos, contexts, chunks int
+	var microsecondsPerCrotchet uint32
+	var lastContext EventContext
+	var chunkData []byte
+
+	var callback = &FuncLexerCallback{
+		OnNoteOn: func(channel uint8, pitch uint8, velocity uint8, time uint32) {
+			notes++
+		},
+		OnTempo: func(bpm uint32, value uint32, time uint32) {
+			tempos++
+			microsecondsPerCrotchet = value
+		},
+		OnBeforeEvent: func(context EventContext) {
+			contexts++
+			lastContext = context
+		},
+		OnUnknownChunk: func(header ChunkHeader, data []byte) {
+			chunks++
+			chunkData = data
+		},
+	}
+
+	assertNoError(NewMidiLexerFromBytes(testMidiFile(), callback).Lex(), t)
+	assertIntsEqual(notes, 3, t)
+	assertIntsEqual(tempos, 1, t)
+	assertUint32Equal(microsecondsPerCrotchet, 500000, t)
+	assertIntsEqual(contexts, 10, t)
+	assertIntsEqual(lastContext.Track, 1, t)
+	assertIntsEqual(chunks, 1, t)
+	assertBytesEqual(chunkData, []byte{0x01, 0x02, 0x03}, t)
+
+	// With nothing set, nothing happens.
+	assertNoError(NewMidiLexerFromBytes(testMidiFile(), new(FuncLexerCallback)).Lex(), t)
+}
+
+// An error for callbacks to return.
+type quotaExceededError struct{}
+
+func (e quotaExceededError) Error() string {
+	return "Quota exceeded"
+}
+
+var quotaExceeded = quotaExceededError{}
+
+// A callback that stops after a number of notes.
+type noteLimitCallback struct {
+	BaseLexerErrorCallback
+	notes int
+	limit int
+}
+
+func (cbk *noteLimitCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) error {
+	cbk.notes++
+
+	if cbk.notes >= cbk.limit {
+		return quotaExceeded
+	}
+
+	return nil
+}
+
+// An error from the callback should stop the lexer, with the position of the event.
+func TestErrorCallbackStops(t *testing.T) {
+	var callback = &noteLimitCallback{limit: 2}
+
+	err := NewMidiLexerFromBytes(testMidiFile(), ErrorCallback(callback)).Lex()
+
+	assertIntsEqual(callback.notes, 2, t)
+	assertTrue(errors.Is(err, quotaExceeded), t)
+
+	var lexError LexError
+	assertTrue(errors.As(err, &lexError), t)
+	assertIntsEqual(int(lexError.Offset), 80, t)
+	assertIntsEqual(lexError.Chunk, 3, t)
+	assertIntsEqual(lexError.Event, 3, t)
+	assertUint8sEqual(lexError.Status, 0x90, t)
+}
+
+// An error from the callback shouldn't be recovered from in LenientMode.
+func TestErrorCallbackStopsLenient(t *testing.T) {
+	var callback = &noteLimitCallback{limit: 1}
+
+	var lexer = NewMidiLexerFromBytes(testMidiFile(), ErrorCallback(callback))
+	lexer.SetMode(LenientMode)
+
+	err := lexer.Lex()
+
+	assertIntsEqual(callback.notes, 1, t)
+	assertTrue(errors.Is(err, quotaExceeded), t)
+}
+
+// A callback that refuses to carry on after errors.
+type stopOnErrorCallback struct {
+	BaseLexerErrorCallback
+	errorReading int
+	finished     int
+}
+
+func (cbk *stopOnErrorCallback) ErrorReading(err error) error {
+	cbk.errorReading++
+	return quotaExceeded
+}
+
+func (cbk *stopOnErrorCallback) Finished() error {
+	cbk.finished++
+	return nil
+}
+
+// Returning an error from ErrorReading should stop the lexer even in LenientMode.
+func TestErrorCallbackErrorReading(t *testing.T) {
+	var data = testMidiFile()
+
+	// Truncate the conductor track's tempo event.
+	data[25] = 0x02
+
+	var callback = new(stopOnErrorCallback)
+	var lexer = NewMidiLexerFromBytes(data, ErrorCallback(callback))
+	lexer.SetMode(LenientMode)
+
+	err := lexer.Lex()
+
+	assertIntsEqual(callback.errorReading, 1, t)
+	assertIntsEqual(callback.finished, 0, t)
+	assertTrue(errors.Is(err, quotaExceeded), t)
+}
+
+// A callback that rejects events in a track.
+type trackLimitCallback struct {
+	BaseLexerErrorCallback
+	events int
+}
+
+func (cbk *trackLimitCallback) BeforeEvent(context EventContext) error {
+	if context.Track > 0 {
+		return quotaExceeded
+	}
+
+	cbk.events++
+	return nil
+}
+
+// An error from BeforeEvent should stop the lexer too.
+func TestErrorCallbackBeforeEvent(t *testing.T) {
+	var callback = new(trackLimitCallback)
+
+	err := NewMidiLexerFromBytes(testMidiFile(), ErrorCallback(callback)).Lex()
+
+	assertIntsEqual(callback.events, 3, t)
+	assertTrue(errors.Is(err, quotaExceeded), t)
+}
+
+// A callback that counts NoteOns.
+type noteCountingErrorCallback struct {
+	BaseLexerErrorCallback
+	noteOns int
+}
+
+func (cbk *noteCountingErrorCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) error {
+	cbk.noteOns++
+	return nil
+}
+
+// A callback that rejects unknown chunks.
+type chunkLimitCallback struct {
+	noteCountingErrorCallback
+	chunkType string
+}
+
+func (cbk *chunkLimitCallback) UnknownChunk(header ChunkHeader, data []byte) error {
+	cbk.chunkType = header.ChunkType
+	return quotaExceeded
+}
+
+// Unknown chunks should be passed to a MidiLexerErrorChunkCallback, and an error should stop the lexer.
+func TestErrorCallbackUnknownChunk(t *testing.T) {
+	var callback = new(chunkLimitCallback)
+
+	// The chunk comes before the track with the notes.
+	err := NewMidiLexerFromBytes(testMidiFile(), ErrorCallback(callback)).Lex()
+
+	assertStringsEqual(callback.chunkType, "XYZZ", t)
+	assertIntsEqual(callback.noteOns, 0, t)
+	assertTrue(errors.Is(err, quotaExceeded), t)
+
+	// Without UnknownChunk the chunk is skipped.
+	var notes = new(noteCountingErrorCallback)
+
+	err = NewMidiLexerFromBytes(testMidiFile(), ErrorCallback(notes)).Lex()
+	assertNoError(err, t)
+	assertIntsEqual(notes.noteOns, 3, t)
+}
+
+// testMidiFileWithLongChunk returns a header and an unknown chunk that's longer than the data.
+// It can be skipped, but not read.
+func testMidiFileWithLongChunk() []byte {
+	return append(testMidiFile()[:14], 0x58, 0x59, 0x5A, 0x5A, 0x00, 0x00, 0x01, 0x00, 0x01, 0x02, 0x03)
+}
+
+// Unknown chunks should only be read by callbacks that want them.
+func TestCallbacksSkipUnknownChunks(t *testing.T) {
+	var _, ok = ErrorCallback(new(BaseLexerErrorCallback)).(MidiLexerChunkCallback)
+	assertFalse(ok, t)
+
+	_, ok = ErrorCallback(new(chunkLimitCallback)).(MidiLexerChunkCallback)
+	assertTrue(ok, t)
+
+	assertNoError(NewMidiLexerFromBytes(testMidiFileWithLongChunk(), ErrorCallback(new(BaseLexerErrorCallback))).Lex(), t)
+	assertNoError(NewMidiLexerFromBytes(testMidiFileWithLongChunk(), new(FuncLexerCallback)).Lex(), t)
+
+	var callback = &FuncLexerCallback{OnUnknownChunk: func(header ChunkHeader, data []byte) {}}
+	var err = NewMidiLexerFromBytes(testMidiFileWithLongChunk(), callback).Lex()
+	assertTrue(errors.Is(err, UnexpectedEndOfFile), t)
+}
+
+// LexContext should stop when the Context is cancelled.
+func TestLexContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var notes = 0
+	var callback = &FuncLexerCallback{OnNoteOn: func(channel uint8, pitch uint8, velocity uint8, time uint32) {
+		notes++
+		cancel()
+	}}
+
+	err := NewMidiLexerFromBytes(testMidiFile(), callback).LexContext(ctx)
+
+	assertIntsEqual(notes, 1, t)
+	assertTrue(errors.Is(err, context.Canceled), t)
+
+	// Already cancelled.
+	notes = 0
+	err = NewMidiLexerFromBytes(testMidiFile(), callback).LexContext(ctx)
+	assertIntsEqual(notes, 0, t)
+	assertTrue(errors.Is(err, context.Canceled), t)
+}
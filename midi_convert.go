@@ -0,0 +1,210 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Conversions between SMF formats.
+ * Each conversion returns a new File, with copies of the events, and leaves the original alone.
+ */
+
+package midi
+
+// ConvertFormat converts a File to the given SMF format.
+// Format 0 can be converted to 1, format 1 to 0 and format 2 to 1 or 0.
+func ConvertFormat(file *File, format uint16) (*File, error) {
+	switch {
+	case file.Format == format:
+		return copyFile(file), nil
+
+	case file.Format == SingleMultiTrackChannel && format == SimultaneousTracks:
+		return SplitChannels(file)
+
+	case file.Format == SimultaneousTracks && format == SingleMultiTrackChannel:
+		return MergeTracks(file)
+
+	case file.Format == SequentialTracks && format == SimultaneousTracks:
+		return FlattenSequence(file)
+
+	case file.Format == SequentialTracks && format == SingleMultiTrackChannel:
+		flattened, err := FlattenSequence(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return MergeTracks(flattened)
+	}
+
+	return nil, WrongFormat
+}
+
+// SplitChannels converts a format 0 file to format 1.
+// The first track is a conductor track with all the events that aren't channel messages,
+// e.g. Tempo and TimeSignature. It's followed by a track for each channel used, in order of channel.
+// Returns BadChannel if a channel message has a channel over 15.
+func SplitChannels(file *File) (*File, error) {
+	if file.Format != SingleMultiTrackChannel {
+		return nil, WrongFormat
+	}
+
+	var conductor Track
+	var channels [16]Track
+	var end uint64 = 0
+
+	for _, track := range file.Tracks {
+		for _, event := range track.Events {
+			end = max(end, event.context().AbsoluteTicks)
+
+			if _, ok := event.(*EndOfTrack); ok {
+				continue
+			}
+
+			event = CopyEvent(event)
+
+			if channelEvent, ok := event.(ChannelEvent); ok {
+				var channel = channelEvent.ChannelNumber()
+
+				if channel > 0x0F {
+					return nil, BadChannel
+				}
+
+				channels[channel].Events = append(channels[channel].Events, event)
+			} else {
+				conductor.Events = append(conductor.Events, event)
+			}
+		}
+	}
+
+	var tracks = []Track{conductor}
+
+	for _, track := range channels {
+		if len(track.Events) > 0 {
+			tracks = append(tracks, track)
+		}
+	}
+
+	return newConvertedFile(file, SimultaneousTracks, tracks, end), nil
+}
+
+// MergeTracks converts a format 1 file to format 0, with all the events in one track in time order.
+// Events at the same time are in order of track, then the order they were in the track.
+func MergeTracks(file *File) (*File, error) {
+	if file.Format != SimultaneousTracks {
+		return nil, WrongFormat
+	}
+
+	var track Track
+	var end uint64 = 0
+
+	for event := range file.MergedEvents() {
+		end = max(end, event.context().AbsoluteTicks)
+
+		if _, ok := event.(*EndOfTrack); !ok {
+			track.Events = append(track.Events, CopyEvent(event))
+		}
+	}
+
+	return newConvertedFile(file, SingleMultiTrackChannel, []Track{track}, end), nil
+}
+
+// FlattenSequence converts a format 2 file, where each track is a pattern played after the one before,
+// to format 1, where the tracks are played at the same time.
+// Each pattern is moved to start when the one before it ends. The first track is a conductor track
+// with the Tempo, TimeSignature, KeySignature and SMPTEOffset events of all the patterns,
+// followed by a track for each pattern with the rest of its events.
+func FlattenSequence(file *File) (*File, error) {
+	if file.Format != SequentialTracks {
+		return nil, WrongFormat
+	}
+
+	var conductor Track
+	var tracks = []Track{conductor}
+
+	// The time at which the pattern starts.
+	var start uint64 = 0
+
+	for _, source := range file.Tracks {
+		var track Track
+		var end = start
+
+		for _, event := range source.Events {
+			event = CopyEvent(event)
+			event.context().AbsoluteTicks += start
+			end = max(end, event.context().AbsoluteTicks)
+
+			switch event.(type) {
+			case *EndOfTrack:
+				continue
+			case *Tempo, *TimeSignature, *KeySignature, *SMPTEOffset:
+				tracks[0].Events = append(tracks[0].Events, event)
+			default:
+				track.Events = append(track.Events, event)
+			}
+		}
+
+		tracks = append(tracks, track)
+		start = end
+	}
+
+	return newConvertedFile(file, SimultaneousTracks, tracks, start), nil
+}
+
+// newConvertedFile makes a File with the header of the original but a different format and tracks.
+// Each track is given an EndOfTrack at the end time, and the events' contexts are updated for their new tracks.
+func newConvertedFile(file *File, format uint16, tracks []Track, end uint64) *File {
+	var result = &File{HeaderData: file.HeaderData, Tracks: tracks}
+	result.Format = format
+	result.NumTracks = uint16(len(tracks))
+
+	for i := range tracks {
+		tracks[i].Events = append(tracks[i].Events, &EndOfTrack{EventContext: EventContext{AbsoluteTicks: end}})
+		updateContexts(tracks[i].Events, i)
+	}
+
+	// Unknown chunks stay before the first track, or after the last.
+	for _, chunk := range file.UnknownChunks {
+		if chunk.BeforeTrack > 0 {
+			chunk.BeforeTrack = len(tracks)
+		}
+
+		result.UnknownChunks = append(result.UnknownChunks, chunk)
+	}
+
+	return result
+}
+
+// updateContexts sets the track index and delta times of the events in a track, from their absolute times.
+func updateContexts(events []Event, track int) {
+	var previousTicks uint64 = 0
+
+	for _, event := range events {
+		var context = event.context()
+
+		context.Track = track
+		context.DeltaTicks = uint32(context.AbsoluteTicks - previousTicks)
+
+		previousTicks = context.AbsoluteTicks
+	}
+}
+
+// copyFile returns a copy of the File, with copies of the events.
+func copyFile(file *File) *File {
+	var result = &File{HeaderData: file.HeaderData, UnknownChunks: append([]UnknownChunk(nil), file.UnknownChunks...)}
+
+	for _, track := range file.Tracks {
+		var events = make([]Event, len(track.Events))
+
+		for i, event := range track.Events {
+			events[i] = CopyEvent(event)
+		}
+
+		result.Tracks = append(result.Tracks, Track{Events: events})
+	}
+
+	return result
+}
@@ -0,0 +1,175 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for format conversions.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// at returns an event's context at the given absolute time.
+func at(ticks uint64) EventContext {
+	return EventContext{AbsoluteTicks: ticks}
+}
+
+// assertTrackTimes asserts the absolute times of the events in a track, and that the contexts are consistent.
+func assertTrackTimes(track Track, index int, expected []uint64, t *testing.T) {
+	assertIntsEqual(len(track.Events), len(expected), t)
+
+	var previous uint64 = 0
+
+	for i, event := range track.Events {
+		var context = event.Context()
+
+		assertIntsEqual(int(context.AbsoluteTicks), int(expected[i]), t)
+		assertIntsEqual(int(context.DeltaTicks), int(context.AbsoluteTicks-previous), t)
+		assertIntsEqual(context.Track, index, t)
+
+		previous = context.AbsoluteTicks
+	}
+
+	_, ok := track.Events[len(track.Events)-1].(*EndOfTrack)
+	assertTrue(ok, t)
+}
+
+// A format 0 file should be split into a conductor track and a track per channel.
+func TestSplitChannels(t *testing.T) {
+	var file = &File{HeaderData: HeaderData{Format: SingleMultiTrackChannel, NumTracks: 1, TicksPerQuarterNote: 96}, Tracks: []Track{{Events: []Event{
+		&Tempo{EventContext: at(0), MicrosecondsPerCrotchet: 500000},
+		&NoteOn{EventContext: at(0), Channel: 9, Pitch: 36, Velocity: 100},
+		&NoteOn{EventContext: at(0), Channel: 0, Pitch: 60, Velocity: 100},
+		&TimeSignature{EventContext: at(48), Numerator: 3, Denominator: 2},
+		&NoteOff{EventContext: at(96), Channel: 0, Pitch: 60},
+		&NoteOn{EventContext: at(96), Channel: 9, Pitch: 36, Velocity: 0},
+		&EndOfTrack{EventContext: at(192)},
+	}}}}
+
+	result, err := SplitChannels(file)
+	assertNoError(err, t)
+
+	assertUint16Equal(result.Format, SimultaneousTracks, t)
+	assertUint16Equal(result.NumTracks, 3, t)
+	assertUint16Equal(result.TicksPerQuarterNote, 96, t)
+
+	assertTrackTimes(result.Tracks[0], 0, []uint64{0, 48, 192}, t)
+	assertTrackTimes(result.Tracks[1], 1, []uint64{0, 96, 192}, t)
+	assertTrackTimes(result.Tracks[2], 2, []uint64{0, 96, 192}, t)
+
+	_, ok := result.Tracks[0].Events[0].(*Tempo)
+	assertTrue(ok, t)
+	assertUint8sEqual(result.Tracks[1].Events[0].(*NoteOn).Channel, 0, t)
+	assertUint8sEqual(result.Tracks[2].Events[0].(*NoteOn).Channel, 9, t)
+
+	// The original shouldn't have changed.
+	assertIntsEqual(file.Tracks[0].Events[1].Context().Track, 0, t)
+	assertIntsEqual(len(file.Tracks), 1, t)
+
+	// Other formats can't be split.
+	_, err = SplitChannels(result)
+	assertError(err, WrongFormat, t)
+}
+
+// A channel message with a channel that doesn't exist can't be given a track.
+func TestSplitChannelsBadChannel(t *testing.T) {
+	var file = &File{HeaderData: HeaderData{Format: SingleMultiTrackChannel, NumTracks: 1}, Tracks: []Track{{Events: []Event{
+		&NoteOn{EventContext: at(0), Channel: 16, Pitch: 60, Velocity: 100},
+	}}}}
+
+	_, err := SplitChannels(file)
+	assertError(err, BadChannel, t)
+}
+
+// A format 1 file should be merged into one track in time order.
+func TestMergeTracks(t *testing.T) {
+	file, err := ReadFileFromBytes(testMidiFile())
+	assertNoError(err, t)
+
+	result, err := MergeTracks(file)
+	assertNoError(err, t)
+
+	assertUint16Equal(result.Format, SingleMultiTrackChannel, t)
+	assertUint16Equal(result.NumTracks, 1, t)
+	assertTrackTimes(result.Tracks[0], 0, []uint64{0, 0, 0, 0, 0, 0, 0x60, 0x60, 0x60}, t)
+
+	// The conductor track's events come first.
+	_, ok := result.Tracks[0].Events[0].(*Tempo)
+	assertTrue(ok, t)
+	_, ok = result.Tracks[0].Events[1].(*TimeSignature)
+	assertTrue(ok, t)
+	_, ok = result.Tracks[0].Events[2].(*SequenceName)
+	assertTrue(ok, t)
+
+	// The unknown chunk went between the tracks, so now it goes after.
+	assertIntsEqual(result.UnknownChunks[0].BeforeTrack, 1, t)
+
+	// It should be a valid file.
+	var buffer bytes.Buffer
+	assertNoError(WriteFile(&buffer, result), t)
+
+	var lexer = NewMidiLexerFromBytes(buffer.Bytes(), new(MockLexerCallback))
+	lexer.SetMode(StrictMode)
+	assertNoError(lexer.Lex(), t)
+}
+
+// Format 2 patterns should be laid end to end.
+func TestFlattenSequence(t *testing.T) {
+	var file = &File{HeaderData: HeaderData{Format: SequentialTracks, NumTracks: 2, TicksPerQuarterNote: 96}, Tracks: []Track{
+		{Events: []Event{
+			&Tempo{EventContext: at(0), MicrosecondsPerCrotchet: 500000},
+			&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 100},
+			&NoteOff{EventContext: at(96), Pitch: 60},
+			&EndOfTrack{EventContext: at(384)},
+		}},
+		{Events: []Event{
+			&Tempo{EventContext: at(0), MicrosecondsPerCrotchet: 400000},
+			&NoteOn{EventContext: at(10), Pitch: 62, Velocity: 100},
+			&NoteOff{EventContext: at(96), Pitch: 62},
+			&EndOfTrack{EventContext: at(96)},
+		}},
+	}}
+
+	result, err := FlattenSequence(file)
+	assertNoError(err, t)
+
+	assertUint16Equal(result.Format, SimultaneousTracks, t)
+	assertUint16Equal(result.NumTracks, 3, t)
+
+	assertTrackTimes(result.Tracks[0], 0, []uint64{0, 384, 480}, t)
+	assertTrackTimes(result.Tracks[1], 1, []uint64{0, 96, 480}, t)
+	assertTrackTimes(result.Tracks[2], 2, []uint64{394, 480, 480}, t)
+
+	assertUint32Equal(result.Tracks[0].Events[1].(*Tempo).MicrosecondsPerCrotchet, 400000, t)
+
+	// Or all the way to format 0.
+	result, err = ConvertFormat(file, SingleMultiTrackChannel)
+	assertNoError(err, t)
+	assertTrackTimes(result.Tracks[0], 0, []uint64{0, 0, 96, 384, 394, 480, 480}, t)
+}
+
+// Conversions that don't make sense should be errors.
+func TestConvertFormat(t *testing.T) {
+	var file = &File{HeaderData: HeaderData{Format: SimultaneousTracks}, Tracks: []Track{{Events: []Event{&EndOfTrack{}}}}}
+
+	_, err := ConvertFormat(file, SequentialTracks)
+	assertError(err, WrongFormat, t)
+
+	// The same format gives a copy.
+	result, err := ConvertFormat(file, SimultaneousTracks)
+	assertNoError(err, t)
+	assertTrue(EventsEqual(result.Tracks[0].Events[0], file.Tracks[0].Events[0]), t)
+
+	if result.Tracks[0].Events[0] == file.Tracks[0].Events[0] {
+		t.Fatal("Expected a copy of the event")
+	}
+}
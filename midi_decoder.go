@@ -0,0 +1,247 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * The decoder.
+ * This runs the lexer one step at a time, turning callbacks into Events, so you can ask for one event at a time.
+ */
+
+package midi
+
+import (
+	"io"
+	"iter"
+)
+
+// Decoder reads Events from a Standard Midi File one at a time.
+type Decoder struct {
+	lexer     *MidiLexer
+	collector *eventCollector
+	finished  bool
+
+	// The error that stopped the lexer. It's returned again rather than carrying on from the middle of an event.
+	err error
+}
+
+// NewDecoder creates a Decoder reading from a Reader.
+func NewDecoder(input io.Reader) *Decoder {
+	var collector = new(eventCollector)
+
+	return &Decoder{lexer: NewMidiLexerFromReader(input, collector), collector: collector}
+}
+
+// NewDecoderFromBytes creates a Decoder reading from MIDI data in memory.
+func NewDecoderFromBytes(data []byte) *Decoder {
+	var collector = new(eventCollector)
+
+	return &Decoder{lexer: NewMidiLexerFromBytes(data, collector), collector: collector}
+}
+
+// SetMode sets the modes of the lexer, e.g. LenientMode. See MidiLexer.SetMode.
+func (decoder *Decoder) SetMode(mode int) {
+	decoder.lexer.SetMode(mode)
+}
+
+// Header returns the SMF header, reading it if it hasn't been read yet.
+func (decoder *Decoder) Header() (HeaderData, error) {
+	for !decoder.collector.began && !decoder.finished {
+		if err := decoder.step(); err != nil {
+			return HeaderData{}, err
+		}
+	}
+
+	return decoder.collector.header, nil
+}
+
+// Next returns the next Event in the file.
+// At the end of the file it returns io.EOF. Other errors are LexErrors, and once there's been one it's always returned.
+func (decoder *Decoder) Next() (Event, error) {
+	for len(decoder.collector.events) == 0 {
+		if decoder.finished {
+			return nil, io.EOF
+		}
+
+		if err := decoder.step(); err != nil {
+			return nil, err
+		}
+	}
+
+	var event = decoder.collector.events[0]
+	decoder.collector.events[0] = nil
+	decoder.collector.events = decoder.collector.events[1:]
+
+	return event, nil
+}
+
+// UnknownChunks returns the chunks read so far that aren't MThd or MTrk, which aren't returned as Events.
+func (decoder *Decoder) UnknownChunks() []UnknownChunk {
+	return decoder.collector.unknownChunks
+}
+
+// Events returns an iterator over the remaining Events in the file.
+// If there's an error, it's yielded with a nil Event and the iteration stops.
+func (decoder *Decoder) Events() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for {
+			event, err := decoder.Next()
+
+			if err == io.EOF {
+				return
+			}
+
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// step runs the lexer for one item.
+func (decoder *Decoder) step() error {
+	if decoder.lexer.input == nil {
+		return NoReadSeeker
+	}
+
+	if decoder.err != nil {
+		return decoder.err
+	}
+
+	finished, err := decoder.lexer.step()
+
+	if err != nil {
+		decoder.err = err
+		return err
+	}
+
+	decoder.finished = finished
+
+	return nil
+}
+
+// eventCollector is a MidiLexerCallback that turns callbacks into Events.
+type eventCollector struct {
+	BaseLexerCallback
+
+	began  bool
+	header HeaderData
+
+	// The position of the event being lexed.
+	context EventContext
+
+	// Events lexed but not yet returned.
+	events []Event
+
+	// The number of MTrk chunks so far.
+	tracks int
+
+	// Chunks that aren't MThd or MTrk.
+	unknownChunks []UnknownChunk
+}
+
+func (cbk *eventCollector) add(event Event) {
+	cbk.events = append(cbk.events, event)
+}
+
+func (cbk *eventCollector) BeforeEvent(context EventContext) { cbk.context = context }
+func (cbk *eventCollector) Header(header HeaderData)         { cbk.header = header }
+func (cbk *eventCollector) Began()                           { cbk.began = true }
+
+func (cbk *eventCollector) Track(header ChunkHeader) {
+	if header.ChunkType == "MTrk" {
+		cbk.tracks++
+	}
+}
+
+func (cbk *eventCollector) UnknownChunk(header ChunkHeader, data []byte) {
+	cbk.unknownChunks = append(cbk.unknownChunks, UnknownChunk{ChunkType: header.ChunkType, Data: data, BeforeTrack: cbk.tracks})
+}
+
+func (cbk *eventCollector) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	cbk.add(&NoteOff{EventContext: cbk.context, Channel: channel, Pitch: pitch, Velocity: velocity})
+}
+func (cbk *eventCollector) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) {
+	cbk.add(&NoteOn{EventContext: cbk.context, Channel: channel, Pitch: pitch, Velocity: velocity})
+}
+func (cbk *eventCollector) PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) {
+	cbk.add(&PolyphonicAfterTouch{EventContext: cbk.context, Channel: channel, Pitch: pitch, Pressure: pressure})
+}
+func (cbk *eventCollector) ControlChange(channel uint8, controller uint8, value uint8, time uint32) {
+	cbk.add(&ControlChange{EventContext: cbk.context, Channel: channel, Controller: controller, Value: value})
+}
+func (cbk *eventCollector) ProgramChange(channel uint8, program uint8, time uint32) {
+	cbk.add(&ProgramChange{EventContext: cbk.context, Channel: channel, Program: program})
+}
+func (cbk *eventCollector) ChannelAfterTouch(channel uint8, value uint8, time uint32) {
+	cbk.add(&ChannelAfterTouch{EventContext: cbk.context, Channel: channel, Value: value})
+}
+func (cbk *eventCollector) PitchWheel(channel uint8, value int16, absValue uint16, time uint32) {
+	cbk.add(&PitchWheel{EventContext: cbk.context, Channel: channel, Value: value, AbsValue: absValue})
+}
+
+func (cbk *eventCollector) SysEx(data []byte, time uint32) {
+	cbk.add(&SysEx{EventContext: cbk.context, Data: data})
+}
+func (cbk *eventCollector) SysExContinuation(data []byte, time uint32) {
+	cbk.add(&SysExContinuation{EventContext: cbk.context, Data: data})
+}
+func (cbk *eventCollector) EscapeSequence(data []byte, time uint32) {
+	cbk.add(&EscapeSequence{EventContext: cbk.context, Data: data})
+}
+
+func (cbk *eventCollector) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32) {
+	cbk.add(&Tempo{EventContext: cbk.context, MicrosecondsPerCrotchet: microsecondsPerCrotchet})
+}
+func (cbk *eventCollector) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
+	cbk.add(&SequenceNumber{EventContext: cbk.context, Number: number, NumberGiven: numberGiven})
+}
+func (cbk *eventCollector) Text(channel uint8, text string, time uint32) {
+	cbk.add(&Text{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) CopyrightText(channel uint8, text string, time uint32) {
+	cbk.add(&CopyrightText{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) SequenceName(channel uint8, text string, time uint32) {
+	cbk.add(&SequenceName{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) TrackInstrumentName(channel uint8, text string, time uint32) {
+	cbk.add(&TrackInstrumentName{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) LyricText(channel uint8, text string, time uint32) {
+	cbk.add(&LyricText{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) MarkerText(channel uint8, text string, time uint32) {
+	cbk.add(&MarkerText{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) CuePointText(channel uint8, text string, time uint32) {
+	cbk.add(&CuePointText{EventContext: cbk.context, Text: text})
+}
+func (cbk *eventCollector) EndOfTrack(channel uint8, time uint32) {
+	cbk.add(&EndOfTrack{EventContext: cbk.context})
+}
+func (cbk *eventCollector) ChannelPrefix(channel uint8, time uint32) {
+	cbk.add(&ChannelPrefix{EventContext: cbk.context, Channel: channel})
+}
+func (cbk *eventCollector) MidiPort(port uint8, time uint32) {
+	cbk.add(&MidiPort{EventContext: cbk.context, Port: port})
+}
+func (cbk *eventCollector) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
+	cbk.add(&TimeSignature{EventContext: cbk.context, Numerator: numerator, Denominator: denomenator, ClocksPerClick: clocksPerClick, DemiSemiQuaverPerQuarter: demiSemiQuaverPerQuarter})
+}
+func (cbk *eventCollector) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) {
+	cbk.add(&KeySignature{EventContext: cbk.context, Key: key, Mode: mode, SharpsOrFlats: sharpsOrFlats})
+}
+func (cbk *eventCollector) SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) {
+	cbk.add(&SMPTEOffset{EventContext: cbk.context, FrameRate: frameRate, Hours: hours, Minutes: minutes, Seconds: seconds, Frames: frames, FractionalFrames: fractionalFrames})
+}
+func (cbk *eventCollector) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) {
+	cbk.add(&SequencerSpecific{EventContext: cbk.context, ManufacturerID: manufacturerID, Data: data})
+}
+func (cbk *eventCollector) UnknownMeta(metaType uint8, data []byte, time uint32) {
+	cbk.add(&UnknownMeta{EventContext: cbk.context, MetaType: metaType, Data: data})
+}
@@ -0,0 +1,189 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the decoder.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// The header should be available before any events.
+func TestDecoderHeader(t *testing.T) {
+	var decoder = NewDecoderFromBytes(testMidiFile())
+
+	header, err := decoder.Header()
+	assertNoError(err, t)
+	assertUint16Equal(header.Format, SimultaneousTracks, t)
+	assertUint16Equal(header.NumTracks, 2, t)
+	assertUint16Equal(header.TicksPerQuarterNote, 96, t)
+
+	// Asking again shouldn't read any further.
+	header, err = decoder.Header()
+	assertNoError(err, t)
+	assertUint16Equal(header.NumTracks, 2, t)
+
+	event, err := decoder.Next()
+	assertNoError(err, t)
+	_, ok := event.(*Tempo)
+	assertTrue(ok, t)
+}
+
+// Next should return every event in order, then io.EOF.
+func TestDecoderNext(t *testing.T) {
+	var decoder = NewDecoder(bytes.NewReader(testMidiFile()))
+
+	var events []Event
+
+	for {
+		event, err := decoder.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		assertNoError(err, t)
+		events = append(events, event)
+	}
+
+	assertIntsEqual(len(events), 10, t)
+
+	tempo := events[0].(*Tempo)
+	assertUint32Equal(tempo.MicrosecondsPerCrotchet, 500000, t)
+	assertIntsEqual(tempo.Track, 0, t)
+
+	timeSignature := events[1].(*TimeSignature)
+	assertUint8sEqual(timeSignature.Numerator, 4, t)
+	assertUint8sEqual(timeSignature.Denominator, 2, t)
+
+	name := events[3].(*SequenceName)
+	assertStringsEqual(name.Text, "Pian", t)
+	assertIntsEqual(name.Track, 1, t)
+
+	sysEx := events[4].(*SysEx)
+	assertBytesEqual(sysEx.Data, []byte{0x7E, 0x7F, 0x09, 0x01, 0xF7}, t)
+
+	// Running status.
+	noteOn := events[6].(*NoteOn)
+	assertUint8sEqual(noteOn.Pitch, 0x40, t)
+
+	noteOn = events[7].(*NoteOn)
+	assertUint8sEqual(noteOn.Pitch, 0x3C, t)
+	assertUint8sEqual(noteOn.Velocity, 0, t)
+	assertUint32Equal(noteOn.Context().DeltaTicks, 0x60, t)
+	assertIntsEqual(int(noteOn.Context().AbsoluteTicks), 0x60, t)
+
+	noteOff := events[8].(*NoteOff)
+	assertUint8sEqual(noteOff.Pitch, 0x40, t)
+	assertIntsEqual(int(noteOff.AbsoluteTicks), 0x60, t)
+
+	_, ok := events[9].(*EndOfTrack)
+	assertTrue(ok, t)
+
+	// And stay at the end.
+	_, err := decoder.Next()
+	assertError(err, io.EOF, t)
+}
+
+// The iterator should yield the same events and allow stopping early.
+func TestDecoderEvents(t *testing.T) {
+	var count = 0
+
+	for event, err := range NewDecoderFromBytes(testMidiFile()).Events() {
+		assertNoError(err, t)
+
+		if event == nil {
+			t.Fatal("Expected an event")
+		}
+
+		count++
+	}
+
+	assertIntsEqual(count, 10, t)
+
+	var decoder = NewDecoderFromBytes(testMidiFile())
+
+	for range decoder.Events() {
+		break
+	}
+
+	// Stopping leaves the rest for later.
+	event, err := decoder.Next()
+	assertNoError(err, t)
+	_, ok := event.(*TimeSignature)
+	assertTrue(ok, t)
+}
+
+// Errors should be yielded once, as a LexError, and end the iteration.
+func TestDecoderEventsError(t *testing.T) {
+	var data = testMidiFile()
+
+	// Cut off in the middle of the second track.
+	data = data[:len(data)-10]
+
+	var errs = 0
+	var events = 0
+
+	for event, err := range NewDecoderFromBytes(data).Events() {
+		if err != nil {
+			errs++
+
+			if event != nil {
+				t.Fatal("Expected no event with an error")
+			}
+
+			if !errors.Is(err, UnexpectedEndOfFile) {
+				t.Fatal("Expected UnexpectedEndOfFile, was ", err)
+			}
+		} else {
+			events++
+		}
+	}
+
+	assertIntsEqual(errs, 1, t)
+	assertIntsEqual(events, 7, t)
+}
+
+// After an error, Next should keep returning it rather than carry on from the middle of an event.
+func TestDecoderNextAfterError(t *testing.T) {
+	var data = testMidiFile()
+
+	// Running status with no status byte before it, where the second track's name should be.
+	data[61] = 0x3C
+
+	var decoder = NewDecoderFromBytes(data)
+	var events = 0
+	var err error
+
+	for err == nil {
+		_, err = decoder.Next()
+		events++
+	}
+
+	assertIntsEqual(events, 4, t)
+	assertTrue(errors.Is(err, NoRunningStatus), t)
+
+	for i := 0; i < 3; i++ {
+		event, again := decoder.Next()
+		assertTrue(event == nil, t)
+		assertTrue(again == err, t)
+	}
+}
+
+// A nil reader should be an error, not a panic.
+func TestDecoderNilReader(t *testing.T) {
+	_, err := NewDecoder(nil).Next()
+	assertError(err, NoReadSeeker, t)
+}
@@ -0,0 +1,383 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * The encoder.
+ * This writes Events to a Standard Midi File, the reverse of the Decoder.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"io"
+)
+
+// Encoder writes a Standard Midi File.
+// Write the header with WriteHeader, then each track with WriteTrack. Or write a whole File with WriteFile.
+type Encoder struct {
+	output io.Writer
+
+	// Modes, e.g. RunningStatusMode.
+	mode int
+
+	// The number of tracks written so far.
+	tracks int
+
+	// A track is written here first so that the length of its chunk is known.
+	buffer bytes.Buffer
+
+	// The status byte of the previous channel message in the track, or zero if there isn't one.
+	runningStatus uint8
+}
+
+// NewEncoder creates an Encoder writing to a Writer.
+func NewEncoder(output io.Writer) *Encoder {
+	return &Encoder{output: output}
+}
+
+// SetMode sets the modes of the encoder, e.g. RunningStatusMode.
+func (encoder *Encoder) SetMode(mode int) {
+	encoder.mode = mode
+}
+
+// WriteFile writes a whole File. The number of tracks in the header is taken from the Tracks.
+func WriteFile(output io.Writer, file *File) error {
+	return NewEncoder(output).WriteFile(file)
+}
+
+// WriteFile writes a whole File. The number of tracks in the header is taken from the Tracks.
+func (encoder *Encoder) WriteFile(file *File) error {
+	var header = file.HeaderData
+	header.NumTracks = uint16(len(file.Tracks))
+
+	var err = encoder.WriteHeader(header)
+
+	if err != nil {
+		return err
+	}
+
+	var chunk = 0
+
+	for i, track := range file.Tracks {
+		// Unknown chunks go back where they were.
+		for ; chunk < len(file.UnknownChunks) && file.UnknownChunks[chunk].BeforeTrack <= i; chunk++ {
+			err = encoder.WriteChunk(file.UnknownChunks[chunk])
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err = encoder.WriteTrack(track)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for ; chunk < len(file.UnknownChunks); chunk++ {
+		err = encoder.WriteChunk(file.UnknownChunks[chunk])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteChunk writes a chunk that isn't MThd or MTrk.
+func (encoder *Encoder) WriteChunk(chunk UnknownChunk) error {
+	encoder.buffer.Reset()
+	encoder.buffer.Write(chunk.Data)
+
+	var err = encoder.writeChunk(chunk.ChunkType)
+
+	if err != nil {
+		return EncodeError{Err: err, Track: -1, Event: -1}
+	}
+
+	return nil
+}
+
+// WriteHeader writes the MThd chunk.
+func (encoder *Encoder) WriteHeader(header HeaderData) error {
+	if header.Format == SingleMultiTrackChannel && header.NumTracks != 1 {
+		return EncodeError{Err: Format0NotSingleTrack, Track: -1, Event: -1}
+	}
+
+	encoder.buffer.Reset()
+
+	var err = writeHeaderData(&encoder.buffer, header)
+
+	if err != nil {
+		return EncodeError{Err: err, Track: -1, Event: -1}
+	}
+
+	return encoder.writeChunk("MThd")
+}
+
+// WriteTrack writes an MTrk chunk.
+// The delta times are taken from the AbsoluteTicks of the events, which must be in order.
+// The status byte of a channel message is left out if it's the same as the one before and either
+// the event has RunningStatus set, or the encoder is in RunningStatusMode.
+// If the last event isn't an EndOfTrack, one is added.
+func (encoder *Encoder) WriteTrack(track Track) error {
+	var trackIndex = encoder.tracks
+	encoder.tracks++
+
+	encoder.buffer.Reset()
+	encoder.runningStatus = 0
+
+	var previousTicks uint64 = 0
+	var endOfTrack = false
+
+	for i, event := range track.Events {
+		var context = event.context()
+
+		if context.AbsoluteTicks < previousTicks {
+			return EncodeError{Err: EventsOutOfOrder, Track: trackIndex, Event: i}
+		}
+
+		if endOfTrack {
+			return EncodeError{Err: EndOfTrackNotLast, Track: trackIndex, Event: i - 1}
+		}
+
+		// Check the delta before converting it for writeVarLength, which would truncate one of 2^32 or more.
+		var delta = context.AbsoluteTicks - previousTicks
+
+		if delta > maxVarLength {
+			return EncodeError{Err: VarLengthTooBig, Track: trackIndex, Event: i}
+		}
+
+		var err = writeVarLength(&encoder.buffer, uint32(delta))
+
+		if err == nil {
+			err = encoder.writeEvent(event, context.RunningStatus)
+		}
+
+		if err != nil {
+			return EncodeError{Err: err, Track: trackIndex, Event: i}
+		}
+
+		previousTicks = context.AbsoluteTicks
+		_, endOfTrack = event.(*EndOfTrack)
+	}
+
+	if !endOfTrack {
+		encoder.buffer.Write([]byte{0x00, 0xFF, 0x2F, 0x00})
+	}
+
+	var err = encoder.writeChunk("MTrk")
+
+	if err != nil {
+		return EncodeError{Err: err, Track: trackIndex, Event: -1}
+	}
+
+	return nil
+}
+
+// writeChunk writes the buffer to the output as a chunk of the given type.
+func (encoder *Encoder) writeChunk(chunkType string) error {
+	var err = writeChunkHeader(encoder.output, ChunkHeader{ChunkType: chunkType, Length: uint32(encoder.buffer.Len())})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = encoder.output.Write(encoder.buffer.Bytes())
+
+	return err
+}
+
+// writeChannelStatus writes the status byte of a channel message, unless running status means it can be left out.
+func (encoder *Encoder) writeChannelStatus(messageType uint8, channel uint8, runningStatus bool) error {
+	if channel > 0x0F {
+		return BadChannel
+	}
+
+	var status = messageType<<4 | channel
+
+	if (runningStatus || encoder.mode&RunningStatusMode != 0) && status == encoder.runningStatus {
+		return nil
+	}
+
+	encoder.runningStatus = status
+
+	return writeUint8(&encoder.buffer, status)
+}
+
+// writeMeta writes a meta event.
+func (encoder *Encoder) writeMeta(metaType uint8, data []byte) error {
+	encoder.runningStatus = 0
+	encoder.buffer.Write([]byte{0xFF, metaType})
+
+	return writeVarLengthData(&encoder.buffer, data)
+}
+
+// writeTextMeta writes a text meta event.
+func (encoder *Encoder) writeTextMeta(metaType uint8, text string) error {
+	encoder.runningStatus = 0
+	encoder.buffer.Write([]byte{0xFF, metaType})
+
+	return writeText(&encoder.buffer, text)
+}
+
+// writeSysEx writes a SysEx event with the given status, F0 or F7.
+func (encoder *Encoder) writeSysEx(status uint8, data []byte) error {
+	encoder.runningStatus = 0
+	encoder.buffer.WriteByte(status)
+
+	return writeVarLengthData(&encoder.buffer, data)
+}
+
+// writeEvent writes an event, after its delta time.
+// If runningStatus is set the status byte of a channel message is left out if possible.
+func (encoder *Encoder) writeEvent(event Event, runningStatus bool) error {
+	var buffer = &encoder.buffer
+	var err error
+
+	switch event := event.(type) {
+
+	// Channel messages
+
+	case *NoteOff:
+		if err = encoder.writeChannelStatus(0x8, event.Channel, runningStatus); err == nil {
+			err = writeTwoUint7(buffer, event.Pitch, event.Velocity)
+		}
+
+	case *NoteOn:
+		if err = encoder.writeChannelStatus(0x9, event.Channel, runningStatus); err == nil {
+			err = writeTwoUint7(buffer, event.Pitch, event.Velocity)
+		}
+
+	case *PolyphonicAfterTouch:
+		if err = encoder.writeChannelStatus(0xA, event.Channel, runningStatus); err == nil {
+			err = writeTwoUint7(buffer, event.Pitch, event.Pressure)
+		}
+
+	case *ControlChange:
+		if err = encoder.writeChannelStatus(0xB, event.Channel, runningStatus); err == nil {
+			err = writeTwoUint7(buffer, event.Controller, event.Value)
+		}
+
+	case *ProgramChange:
+		if err = encoder.writeChannelStatus(0xC, event.Channel, runningStatus); err == nil {
+			err = writeUint7(buffer, event.Program)
+		}
+
+	case *ChannelAfterTouch:
+		if err = encoder.writeChannelStatus(0xD, event.Channel, runningStatus); err == nil {
+			err = writeUint7(buffer, event.Value)
+		}
+
+	case *PitchWheel:
+		if err = encoder.writeChannelStatus(0xE, event.Channel, runningStatus); err == nil {
+			err = writePitchWheelValue(buffer, event.Value)
+		}
+
+	// System Exclusive events
+
+	case *SysEx:
+		err = encoder.writeSysEx(0xF0, event.Data)
+
+	case *SysExContinuation:
+		err = encoder.writeSysEx(0xF7, event.Data)
+
+	case *EscapeSequence:
+		err = encoder.writeSysEx(0xF7, event.Data)
+
+	// Meta events
+
+	case *SequenceNumber:
+		if !event.NumberGiven {
+			err = encoder.writeMeta(0x00, nil)
+		} else {
+			err = encoder.writeMeta(0x00, []byte{byte(event.Number >> 8), byte(event.Number)})
+		}
+
+	case *Text:
+		err = encoder.writeTextMeta(0x01, event.Text)
+
+	case *CopyrightText:
+		err = encoder.writeTextMeta(0x02, event.Text)
+
+	case *SequenceName:
+		err = encoder.writeTextMeta(0x03, event.Text)
+
+	case *TrackInstrumentName:
+		err = encoder.writeTextMeta(0x04, event.Text)
+
+	case *LyricText:
+		err = encoder.writeTextMeta(0x05, event.Text)
+
+	case *MarkerText:
+		err = encoder.writeTextMeta(0x06, event.Text)
+
+	case *CuePointText:
+		err = encoder.writeTextMeta(0x07, event.Text)
+
+	case *EndOfTrack:
+		err = encoder.writeMeta(0x2F, nil)
+
+	case *Tempo:
+		// A tempo of zero, or too big to fit in 3 bytes, can't be read back.
+		if event.MicrosecondsPerCrotchet == 0 || event.MicrosecondsPerCrotchet > 0xFFFFFF {
+			return InvalidTempo
+		}
+
+		var data = []byte{byte(event.MicrosecondsPerCrotchet >> 16), byte(event.MicrosecondsPerCrotchet >> 8), byte(event.MicrosecondsPerCrotchet)}
+		err = encoder.writeMeta(0x51, data)
+
+	case *SMPTEOffset:
+		// The hours byte is 0rrhhhhh, where rr is the frame rate.
+		var rate = -1
+		for i, frameRate := range smpteOffsetFrameRates {
+			if frameRate == event.FrameRate {
+				rate = i
+			}
+		}
+
+		if rate == -1 {
+			return UnsupportedTimeCodeFormat
+		}
+
+		var data = []byte{byte(rate)<<5 | event.Hours&0x1F, event.Minutes, event.Seconds, event.Frames, event.FractionalFrames}
+		err = encoder.writeMeta(0x54, data)
+
+	case *ChannelPrefix:
+		err = encoder.writeMeta(0x20, []byte{event.Channel})
+
+	case *MidiPort:
+		err = encoder.writeMeta(0x21, []byte{event.Port})
+
+	case *TimeSignature:
+		err = encoder.writeMeta(0x58, []byte{event.Numerator, event.Denominator, event.ClocksPerClick, event.DemiSemiQuaverPerQuarter})
+
+	case *KeySignature:
+		// The Key is worked out from the sharps or flats when reading, so only they are written.
+		err = encoder.writeMeta(0x59, []byte{byte(event.SharpsOrFlats), byte(event.Mode)})
+
+	case *SequencerSpecific:
+		var data = make([]byte, 0, len(event.ManufacturerID)+len(event.Data))
+		data = append(data, event.ManufacturerID...)
+		data = append(data, event.Data...)
+
+		err = encoder.writeMeta(0x7F, data)
+
+	case *UnknownMeta:
+		err = encoder.writeMeta(event.MetaType, event.Data)
+
+	default:
+		err = UnknownEvent
+	}
+
+	return err
+}
@@ -0,0 +1,219 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the encoder.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// allEventsTrack returns a track with one of every type of event.
+func allEventsTrack() Track {
+	var events = []Event{
+		&SequenceNumber{Number: 0x0102, NumberGiven: true},
+		&SequenceNumber{},
+		&Text{Text: "Text"},
+		&CopyrightText{Text: "Copyright"},
+		&SequenceName{Text: "Sequence"},
+		&TrackInstrumentName{Text: "Instrument"},
+		&LyricText{Text: "Lyric"},
+		&MarkerText{Text: "Marker"},
+		&CuePointText{Text: "Cue"},
+		&Tempo{MicrosecondsPerCrotchet: 500000},
+		&SMPTEOffset{FrameRate: SMPTE30DropFrame, Hours: 1, Minutes: 2, Seconds: 3, Frames: 4, FractionalFrames: 5},
+		&TimeSignature{Numerator: 6, Denominator: 3, ClocksPerClick: 36, DemiSemiQuaverPerQuarter: 8},
+		&KeySignature{Key: DegreeE, Mode: MinorMode, SharpsOrFlats: 1},
+		&SequencerSpecific{ManufacturerID: []byte{0x00, 0x20, 0x30}, Data: []byte{0x01}},
+		&UnknownMeta{MetaType: 0x60, Data: []byte{0x01, 0x02}},
+		&ChannelPrefix{Channel: 9},
+		&MidiPort{Port: 2},
+		&SysEx{Data: []byte{0x43, 0x12}},
+		&SysExContinuation{Data: []byte{0x00, 0xF7}},
+		&EscapeSequence{Data: []byte{0xF8}},
+		&NoteOn{Channel: 1, Pitch: 60, Velocity: 64},
+		&NoteOff{Channel: 1, Pitch: 60, Velocity: 10},
+		&PolyphonicAfterTouch{Channel: 2, Pitch: 61, Pressure: 20},
+		&ControlChange{Channel: 3, Controller: 64, Value: 127},
+		&ProgramChange{Channel: 4, Program: 5},
+		&ChannelAfterTouch{Channel: 5, Value: 30},
+		&PitchWheel{Channel: 15, Value: -100, AbsValue: 0x2000 - 100},
+		&EndOfTrack{},
+	}
+
+	// An event every 200 ticks.
+	for i, event := range events {
+		var context = event.context()
+		context.AbsoluteTicks = uint64(i) * 200
+
+		if i > 0 {
+			context.DeltaTicks = 200
+		}
+	}
+
+	return Track{Events: events}
+}
+
+// Writing then reading every type of event should give the same events.
+func TestEncoderAllEvents(t *testing.T) {
+	var file = &File{HeaderData: HeaderData{Format: SingleMultiTrackChannel, TicksPerQuarterNote: 480}, Tracks: []Track{allEventsTrack()}}
+
+	for _, mode := range []int{0, RunningStatusMode} {
+		var buffer bytes.Buffer
+		var encoder = NewEncoder(&buffer)
+		encoder.SetMode(mode)
+
+		assertNoError(encoder.WriteFile(file), t)
+
+		var lexer = NewMidiLexerFromBytes(buffer.Bytes(), new(MockLexerCallback))
+		lexer.SetMode(StrictMode)
+		assertNoError(lexer.Lex(), t)
+
+		result, err := ReadFileFromBytes(buffer.Bytes())
+		assertNoError(err, t)
+
+		assertUint16Equal(result.Format, SingleMultiTrackChannel, t)
+		assertUint16Equal(result.NumTracks, 1, t)
+		assertUint16Equal(result.TicksPerQuarterNote, 480, t)
+		assertIntsEqual(len(result.Tracks[0].Events), len(file.Tracks[0].Events), t)
+
+		for i, event := range result.Tracks[0].Events {
+			// The offset in the file wasn't known before writing.
+			event.context().Offset = 0
+
+			if !EventsEqual(event, file.Tracks[0].Events[i]) {
+				t.Fatal("Expected ", file.Tracks[0].Events[i], " got ", event)
+			}
+		}
+	}
+}
+
+// The test file should be written exactly as it was read, including the unknown chunk and running status.
+func TestEncoderRoundTrip(t *testing.T) {
+	file, err := ReadFileFromBytes(testMidiFile())
+	assertNoError(err, t)
+
+	assertIntsEqual(len(file.UnknownChunks), 1, t)
+	assertStringsEqual(file.UnknownChunks[0].ChunkType, "XYZZ", t)
+	assertIntsEqual(file.UnknownChunks[0].BeforeTrack, 1, t)
+	assertBytesEqual(file.UnknownChunks[0].Data, []byte{0x01, 0x02, 0x03}, t)
+
+	var buffer bytes.Buffer
+	assertNoError(WriteFile(&buffer, file), t)
+	assertBytesEqual(buffer.Bytes(), testMidiFile(), t)
+
+	// The same in RunningStatusMode, as there's nowhere else it could be used.
+	buffer.Reset()
+	var encoder = NewEncoder(&buffer)
+	encoder.SetMode(RunningStatusMode)
+	assertNoError(encoder.WriteFile(file), t)
+	assertBytesEqual(buffer.Bytes(), testMidiFile(), t)
+
+	// Without running status there are two more status bytes.
+	for _, event := range file.Tracks[1].Events {
+		event.context().RunningStatus = false
+	}
+
+	buffer.Reset()
+	assertNoError(WriteFile(&buffer, file), t)
+	assertIntsEqual(buffer.Len(), len(testMidiFile())+2, t)
+}
+
+// Unknown chunks should be written back in their place, even at the start or end.
+func TestEncoderUnknownChunks(t *testing.T) {
+	var file = &File{
+		HeaderData:    HeaderData{Format: SimultaneousTracks, TicksPerQuarterNote: 96},
+		Tracks:        []Track{Track{}},
+		UnknownChunks: []UnknownChunk{{"AAAA", []byte{0x01}, 0}, {"BBBB", nil, 0}, {"CCCC", []byte{0x02, 0x03}, 1}},
+	}
+
+	var buffer bytes.Buffer
+	assertNoError(WriteFile(&buffer, file), t)
+
+	assertBytesEqual(buffer.Bytes()[14:], []byte{
+		0x41, 0x41, 0x41, 0x41, 0x00, 0x00, 0x00, 0x01, 0x01,
+		0x42, 0x42, 0x42, 0x42, 0x00, 0x00, 0x00, 0x00,
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, 0x00, 0xFF, 0x2F, 0x00,
+		0x43, 0x43, 0x43, 0x43, 0x00, 0x00, 0x00, 0x02, 0x02, 0x03,
+	}, t)
+
+	result, err := ReadFileFromBytes(buffer.Bytes())
+	assertNoError(err, t)
+	assertIntsEqual(len(result.UnknownChunks), 3, t)
+
+	for i, chunk := range result.UnknownChunks {
+		assertStringsEqual(chunk.ChunkType, file.UnknownChunks[i].ChunkType, t)
+		assertBytesEqual(chunk.Data, file.UnknownChunks[i].Data, t)
+		assertIntsEqual(chunk.BeforeTrack, file.UnknownChunks[i].BeforeTrack, t)
+	}
+}
+
+// Time code division should be written.
+func TestEncoderTimeCodeHeader(t *testing.T) {
+	var header = HeaderData{Format: SimultaneousTracks, NumTracks: 0, TimeFormat: TimeCodeTimeFormat, FrameRate: SMPTE25, TicksPerFrame: 40}
+
+	var buffer bytes.Buffer
+	assertNoError(NewEncoder(&buffer).WriteHeader(header), t)
+
+	assertBytesEqual(buffer.Bytes(), []byte{0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x00, 0xE7, 0x28}, t)
+}
+
+// Tracks without an EndOfTrack should get one, at the time of the last event.
+func TestEncoderAddsEndOfTrack(t *testing.T) {
+	var buffer bytes.Buffer
+	var track = Track{Events: []Event{&NoteOn{EventContext: EventContext{AbsoluteTicks: 0x60}, Pitch: 60, Velocity: 64}}}
+
+	assertNoError(NewEncoder(&buffer).WriteTrack(track), t)
+
+	assertBytesEqual(buffer.Bytes(), []byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x08, 0x60, 0x90, 0x3C, 0x40, 0x00, 0xFF, 0x2F, 0x00}, t)
+}
+
+// Events that can't be written should be errors, saying which event it was.
+func TestEncoderErrors(t *testing.T) {
+	var tests = []struct {
+		events   []Event
+		expected error
+		event    int
+	}{
+		{[]Event{&NoteOn{EventContext: EventContext{AbsoluteTicks: 10}}, &NoteOff{EventContext: EventContext{AbsoluteTicks: 5}}}, EventsOutOfOrder, 1},
+		{[]Event{&NoteOn{Channel: 16}}, BadChannel, 0},
+		{[]Event{&Text{}, &NoteOn{Pitch: 0x80}}, BadDataByte, 1},
+		{[]Event{&Tempo{}}, InvalidTempo, 0},
+		{[]Event{&SMPTEOffset{FrameRate: 26}}, UnsupportedTimeCodeFormat, 0},
+		{[]Event{&EndOfTrack{}, &Text{}}, EndOfTrackNotLast, 0},
+		{[]Event{&Text{}, &NoteOn{EventContext: EventContext{AbsoluteTicks: 0x10000000}}}, VarLengthTooBig, 1},
+		{[]Event{&NoteOn{EventContext: EventContext{AbsoluteTicks: 1 << 32}}}, VarLengthTooBig, 0},
+	}
+
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		var encoder = NewEncoder(&buffer)
+
+		// The second track, so the index is 1.
+		assertNoError(encoder.WriteTrack(Track{}), t)
+		err := encoder.WriteTrack(Track{Events: test.events})
+
+		assertTrue(errors.Is(err, test.expected), t)
+
+		var encodeError EncodeError
+		assertTrue(errors.As(err, &encodeError), t)
+		assertIntsEqual(encodeError.Track, 1, t)
+		assertIntsEqual(encodeError.Event, test.event, t)
+	}
+
+	// Format 0 must have one track.
+	var buffer bytes.Buffer
+	err := WriteFile(&buffer, &File{Tracks: []Track{Track{}, Track{}}})
+	assertTrue(errors.Is(err, Format0NotSingleTrack), t)
+}
@@ -14,6 +14,10 @@
 
 package midi
 
+import (
+	"fmt"
+)
+
 // A load of Errors and single values for convenience.
 
 type UnexpectedEventLengthError struct {
@@ -77,3 +81,201 @@ func (e BadSizeChunkError) Error() string {
 }
 
 var BadSizeChunk = BadSizeChunkError{}
+
+type NoRunningStatusError struct{}
+
+func (e NoRunningStatusError) Error() string {
+	return "Data byte found where status byte expected, but there was no running status."
+}
+
+var NoRunningStatus = NoRunningStatusError{}
+
+type InvalidTempoError struct{}
+
+func (e InvalidTempoError) Error() string {
+	return "Tempo of zero microseconds per crotchet."
+}
+
+var InvalidTempo = InvalidTempoError{}
+
+type UnsupportedTimeCodeFormatError struct{}
+
+func (e UnsupportedTimeCodeFormatError) Error() string {
+	return "The SMPTE frame rate was not one of 24, 25, 29 or 30."
+}
+
+var UnsupportedTimeCodeFormat = UnsupportedTimeCodeFormatError{}
+
+type NotTimeCodeTimeFormatError struct{}
+
+func (e NotTimeCodeTimeFormatError) Error() string {
+	return "The file does not use TimeCodeTimeFormat."
+}
+
+var NotTimeCodeTimeFormat = NotTimeCodeTimeFormatError{}
+
+type MissingEndOfTrackError struct{}
+
+func (e MissingEndOfTrackError) Error() string {
+	return "Reached the end of the track chunk without an EndOfTrack event."
+}
+
+var MissingEndOfTrack = MissingEndOfTrackError{}
+
+type WrongNumberOfTracksError struct{}
+
+func (e WrongNumberOfTracksError) Error() string {
+	return "The number of tracks didn't match the SMF header."
+}
+
+var WrongNumberOfTracks = WrongNumberOfTracksError{}
+
+type Format0NotSingleTrackError struct{}
+
+func (e Format0NotSingleTrackError) Error() string {
+	return "A format 0 SMF must have exactly one track."
+}
+
+var Format0NotSingleTrack = Format0NotSingleTrackError{}
+
+type BadDataByteError struct{}
+
+func (e BadDataByteError) Error() string {
+	return "Data byte had the high bit set."
+}
+
+var BadDataByte = BadDataByteError{}
+
+type CannotSeekError struct{}
+
+func (e CannotSeekError) Error() string {
+	return "Can't seek backwards or from the end of a Reader."
+}
+
+var CannotSeek = CannotSeekError{}
+
+type BadChannelError struct{}
+
+func (e BadChannelError) Error() string {
+	return "Channel must be 0 to 15."
+}
+
+var BadChannel = BadChannelError{}
+
+type EventsOutOfOrderError struct{}
+
+func (e EventsOutOfOrderError) Error() string {
+	return "Event is earlier than the previous event in the track."
+}
+
+var EventsOutOfOrder = EventsOutOfOrderError{}
+
+type UnknownEventError struct{}
+
+func (e UnknownEventError) Error() string {
+	return "Event type can't be written."
+}
+
+var UnknownEvent = UnknownEventError{}
+
+type VarLengthTooBigError struct{}
+
+func (e VarLengthTooBigError) Error() string {
+	return "Variable length value is bigger than 0x0FFFFFFF."
+}
+
+var VarLengthTooBig = VarLengthTooBigError{}
+
+type EndOfTrackNotLastError struct{}
+
+func (e EndOfTrackNotLastError) Error() string {
+	return "EndOfTrack must be the last event in the track."
+}
+
+var EndOfTrackNotLast = EndOfTrackNotLastError{}
+
+type WrongFormatError struct{}
+
+func (e WrongFormatError) Error() string {
+	return "File is the wrong SMF format for this conversion."
+}
+
+var WrongFormat = WrongFormatError{}
+
+type NotMetricalTimeFormatError struct{}
+
+func (e NotMetricalTimeFormatError) Error() string {
+	return "Not metrical time format, or no ticks per quarter note."
+}
+
+var NotMetricalTimeFormat = NotMetricalTimeFormatError{}
+
+type InvalidTimeSignatureError struct{}
+
+func (e InvalidTimeSignatureError) Error() string {
+	return "Time signature has no beats, or beats that aren't a whole number of ticks."
+}
+
+var InvalidTimeSignature = InvalidTimeSignatureError{}
+
+type InvalidPositionError struct{}
+
+func (e InvalidPositionError) Error() string {
+	return "Bar, beat or tick out of range."
+}
+
+var InvalidPosition = InvalidPositionError{}
+
+type InvalidQuantizeSettingsError struct{}
+
+func (e InvalidQuantizeSettingsError) Error() string {
+	return "Quantize grid doesn't fit the ticks per quarter note, or strength, swing or window out of range."
+}
+
+var InvalidQuantizeSettings = InvalidQuantizeSettingsError{}
+
+// LexError is returned by MidiLexer.Lex. It wraps the underlying error, which may be one of the values above,
+// with where in the file the error happened. Use errors.Is or errors.As to find the underlying error.
+type LexError struct {
+	Err error
+
+	// Byte offset in the file of the start of the chunk header or track event being lexed.
+	Offset int64
+
+	// Index of the chunk in the file, where the MThd header chunk is 0.
+	Chunk int
+
+	// Index of the event in the track, starting at 0. -1 if the error wasn't in a track event.
+	Event int
+
+	// The status byte of the track event, if it was read, otherwise 0.
+	Status uint8
+}
+
+func (e LexError) Error() string {
+	return fmt.Sprintf("%s (offset %d, chunk %d, event %d, status 0x%02X)", e.Err, e.Offset, e.Chunk, e.Event, e.Status)
+}
+
+func (e LexError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeError is returned by the Encoder. It wraps the underlying error, which may be one of the values above,
+// with the event that couldn't be written.
+type EncodeError struct {
+	Err error
+
+	// Index of the track being written, starting at 0. -1 if the error wasn't in a track.
+	Track int
+
+	// Index of the event in the track, starting at 0. -1 if the error wasn't in an event.
+	Event int
+}
+
+func (e EncodeError) Error() string {
+	return fmt.Sprintf("%s (track %d, event %d)", e.Err, e.Track, e.Event)
+}
+
+func (e EncodeError) Unwrap() error {
+	return e.Err
+}
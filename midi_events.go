@@ -0,0 +1,467 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Events.
+ * Values for each of the track events that the lexer finds, as an alternative to the callback arguments.
+ */
+
+package midi
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Event is a track event. It is one of the event types below, as a pointer, and no others.
+type Event interface {
+	// Context returns the position of the event.
+	Context() EventContext
+
+	// String describes the event and its position.
+	String() string
+
+	// context returns the position of the event so it can be changed.
+	context() *EventContext
+
+	// isEvent is only implemented by the event types below.
+	isEvent()
+}
+
+// ChannelEvent is an Event that is sent on a channel, i.e. a channel voice message.
+type ChannelEvent interface {
+	Event
+
+	// ChannelNumber returns the channel, 0 to 15.
+	ChannelNumber() uint8
+}
+
+// Context returns the position of the event.
+func (context *EventContext) Context() EventContext {
+	return *context
+}
+
+func (context *EventContext) context() *EventContext {
+	return context
+}
+
+// String describes the position of the event.
+func (context EventContext) String() string {
+	return fmt.Sprintf("track %d, tick %d (+%d)", context.Track, context.AbsoluteTicks, context.DeltaTicks)
+}
+
+// SortEvents sorts events into time order.
+// The sort is stable, so events at the same time stay in the order they were in, except that events from lower numbered tracks come first.
+func SortEvents(events []Event) {
+	slices.SortStableFunc(events, func(a Event, b Event) int {
+		var aContext, bContext = a.context(), b.context()
+
+		if aContext.AbsoluteTicks != bContext.AbsoluteTicks {
+			return cmp.Compare(aContext.AbsoluteTicks, bContext.AbsoluteTicks)
+		}
+
+		return cmp.Compare(aContext.Track, bContext.Track)
+	})
+}
+
+// CopyEvent returns a copy of the event, so it can be changed without changing the original.
+// Data slices are shared with the original.
+func CopyEvent(event Event) Event {
+	var value = reflect.ValueOf(event).Elem()
+	var result = reflect.New(value.Type())
+	result.Elem().Set(value)
+
+	return result.Interface().(Event)
+}
+
+// EventsEqual returns true if the two events are of the same type with the same values and position.
+func EventsEqual(a Event, b Event) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// Channel messages
+
+type NoteOff struct {
+	EventContext
+	Channel  uint8
+	Pitch    uint8
+	Velocity uint8
+}
+
+type NoteOn struct {
+	EventContext
+	Channel  uint8
+	Pitch    uint8
+	Velocity uint8
+}
+
+type PolyphonicAfterTouch struct {
+	EventContext
+	Channel  uint8
+	Pitch    uint8
+	Pressure uint8
+}
+
+type ControlChange struct {
+	EventContext
+	Channel    uint8
+	Controller uint8
+	Value      uint8
+}
+
+type ProgramChange struct {
+	EventContext
+	Channel uint8
+	Program uint8
+}
+
+type ChannelAfterTouch struct {
+	EventContext
+	Channel uint8
+	Value   uint8
+}
+
+// The Value is signed relative to the centre, AbsValue is the value in the file.
+// The Encoder writes the Value.
+type PitchWheel struct {
+	EventContext
+	Channel  uint8
+	Value    int16
+	AbsValue uint16
+}
+
+// System Exclusive events
+// The Data is as found in the file. See MidiLexerCallback.
+
+type SysEx struct {
+	EventContext
+	Data []byte
+}
+
+type SysExContinuation struct {
+	EventContext
+	Data []byte
+}
+
+type EscapeSequence struct {
+	EventContext
+	Data []byte
+}
+
+// Meta events
+
+type SequenceNumber struct {
+	EventContext
+	Number      uint16
+	NumberGiven bool
+}
+
+type Text struct {
+	EventContext
+	Text string
+}
+
+type CopyrightText struct {
+	EventContext
+	Text string
+}
+
+type SequenceName struct {
+	EventContext
+	Text string
+}
+
+type TrackInstrumentName struct {
+	EventContext
+	Text string
+}
+
+type LyricText struct {
+	EventContext
+	Text string
+}
+
+type MarkerText struct {
+	EventContext
+	Text string
+}
+
+type CuePointText struct {
+	EventContext
+	Text string
+}
+
+type EndOfTrack struct {
+	EventContext
+}
+
+// The channel that following meta and SysEx events apply to. Obsolete.
+type ChannelPrefix struct {
+	EventContext
+	Channel uint8
+}
+
+// The output port for the track. Obsolete.
+type MidiPort struct {
+	EventContext
+	Port uint8
+}
+
+type Tempo struct {
+	EventContext
+	MicrosecondsPerCrotchet uint32
+}
+
+// The Denominator is a power of 2, as in the file.
+type TimeSignature struct {
+	EventContext
+	Numerator                uint8
+	Denominator              uint8
+	ClocksPerClick           uint8
+	DemiSemiQuaverPerQuarter uint8
+}
+
+type KeySignature struct {
+	EventContext
+	Key           ScaleDegree
+	Mode          KeySignatureMode
+	SharpsOrFlats int8
+}
+
+type SMPTEOffset struct {
+	EventContext
+	FrameRate        SMPTEFrameRate
+	Hours            uint8
+	Minutes          uint8
+	Seconds          uint8
+	Frames           uint8
+	FractionalFrames uint8
+}
+
+type SequencerSpecific struct {
+	EventContext
+	ManufacturerID []byte
+	Data           []byte
+}
+
+type UnknownMeta struct {
+	EventContext
+	MetaType uint8
+	Data     []byte
+}
+
+// Event methods.
+
+func (event *NoteOff) isEvent() {}
+
+func (event *NoteOff) String() string {
+	return fmt.Sprintf("NoteOff channel %d, pitch %d, velocity %d at %s", event.Channel, event.Pitch, event.Velocity, event.EventContext)
+}
+
+func (event *NoteOff) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *NoteOn) isEvent() {}
+
+func (event *NoteOn) String() string {
+	return fmt.Sprintf("NoteOn channel %d, pitch %d, velocity %d at %s", event.Channel, event.Pitch, event.Velocity, event.EventContext)
+}
+
+func (event *NoteOn) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *PolyphonicAfterTouch) isEvent() {}
+
+func (event *PolyphonicAfterTouch) String() string {
+	return fmt.Sprintf("PolyphonicAfterTouch channel %d, pitch %d, pressure %d at %s", event.Channel, event.Pitch, event.Pressure, event.EventContext)
+}
+
+func (event *PolyphonicAfterTouch) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *ControlChange) isEvent() {}
+
+func (event *ControlChange) String() string {
+	return fmt.Sprintf("ControlChange channel %d, controller %d, value %d at %s", event.Channel, event.Controller, event.Value, event.EventContext)
+}
+
+func (event *ControlChange) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *ProgramChange) isEvent() {}
+
+func (event *ProgramChange) String() string {
+	return fmt.Sprintf("ProgramChange channel %d, program %d at %s", event.Channel, event.Program, event.EventContext)
+}
+
+func (event *ProgramChange) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *ChannelAfterTouch) isEvent() {}
+
+func (event *ChannelAfterTouch) String() string {
+	return fmt.Sprintf("ChannelAfterTouch channel %d, value %d at %s", event.Channel, event.Value, event.EventContext)
+}
+
+func (event *ChannelAfterTouch) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *PitchWheel) isEvent() {}
+
+func (event *PitchWheel) String() string {
+	return fmt.Sprintf("PitchWheel channel %d, value %d at %s", event.Channel, event.Value, event.EventContext)
+}
+
+func (event *PitchWheel) ChannelNumber() uint8 {
+	return event.Channel
+}
+
+func (event *SysEx) isEvent() {}
+
+func (event *SysEx) String() string {
+	return fmt.Sprintf("SysEx % X at %s", event.Data, event.EventContext)
+}
+
+func (event *SysExContinuation) isEvent() {}
+
+func (event *SysExContinuation) String() string {
+	return fmt.Sprintf("SysExContinuation % X at %s", event.Data, event.EventContext)
+}
+
+func (event *EscapeSequence) isEvent() {}
+
+func (event *EscapeSequence) String() string {
+	return fmt.Sprintf("EscapeSequence % X at %s", event.Data, event.EventContext)
+}
+
+func (event *SequenceNumber) isEvent() {}
+
+func (event *SequenceNumber) String() string {
+	if !event.NumberGiven {
+		return fmt.Sprintf("SequenceNumber (track number) at %s", event.EventContext)
+	}
+
+	return fmt.Sprintf("SequenceNumber %d at %s", event.Number, event.EventContext)
+}
+
+func (event *Text) isEvent() {}
+
+func (event *Text) String() string {
+	return fmt.Sprintf("Text %q at %s", event.Text, event.EventContext)
+}
+
+func (event *CopyrightText) isEvent() {}
+
+func (event *CopyrightText) String() string {
+	return fmt.Sprintf("CopyrightText %q at %s", event.Text, event.EventContext)
+}
+
+func (event *SequenceName) isEvent() {}
+
+func (event *SequenceName) String() string {
+	return fmt.Sprintf("SequenceName %q at %s", event.Text, event.EventContext)
+}
+
+func (event *TrackInstrumentName) isEvent() {}
+
+func (event *TrackInstrumentName) String() string {
+	return fmt.Sprintf("TrackInstrumentName %q at %s", event.Text, event.EventContext)
+}
+
+func (event *LyricText) isEvent() {}
+
+func (event *LyricText) String() string {
+	return fmt.Sprintf("LyricText %q at %s", event.Text, event.EventContext)
+}
+
+func (event *MarkerText) isEvent() {}
+
+func (event *MarkerText) String() string {
+	return fmt.Sprintf("MarkerText %q at %s", event.Text, event.EventContext)
+}
+
+func (event *CuePointText) isEvent() {}
+
+func (event *CuePointText) String() string {
+	return fmt.Sprintf("CuePointText %q at %s", event.Text, event.EventContext)
+}
+
+func (event *EndOfTrack) isEvent() {}
+
+func (event *EndOfTrack) String() string {
+	return fmt.Sprintf("EndOfTrack at %s", event.EventContext)
+}
+
+func (event *ChannelPrefix) isEvent() {}
+
+func (event *ChannelPrefix) String() string {
+	return fmt.Sprintf("ChannelPrefix %d at %s", event.Channel, event.EventContext)
+}
+
+func (event *MidiPort) isEvent() {}
+
+func (event *MidiPort) String() string {
+	return fmt.Sprintf("MidiPort %d at %s", event.Port, event.EventContext)
+}
+
+func (event *Tempo) isEvent() {}
+
+func (event *Tempo) String() string {
+	return fmt.Sprintf("Tempo %d microseconds per crotchet at %s", event.MicrosecondsPerCrotchet, event.EventContext)
+}
+
+// BPM returns the tempo in beats (crotchets) per minute, without rounding.
+func (event *Tempo) BPM() float64 {
+	return 60000000 / float64(event.MicrosecondsPerCrotchet)
+}
+
+func (event *TimeSignature) isEvent() {}
+
+func (event *TimeSignature) String() string {
+	return fmt.Sprintf("TimeSignature %d/%d, %d clocks per click, %d demisemiquavers per quarter at %s", event.Numerator, event.DenominatorValue(), event.ClocksPerClick, event.DemiSemiQuaverPerQuarter, event.EventContext)
+}
+
+// DenominatorValue returns the denominator as it's written in music, e.g. 8 for 6/8.
+func (event *TimeSignature) DenominatorValue() int {
+	return 1 << event.Denominator
+}
+
+func (event *KeySignature) isEvent() {}
+
+func (event *KeySignature) String() string {
+	return fmt.Sprintf("KeySignature key %d, mode %d, sharps or flats %d at %s", event.Key, event.Mode, event.SharpsOrFlats, event.EventContext)
+}
+
+func (event *SMPTEOffset) isEvent() {}
+
+func (event *SMPTEOffset) String() string {
+	var timecode = SMPTETimecode{FrameRate: event.FrameRate, Hours: event.Hours, Minutes: event.Minutes, Seconds: event.Seconds, Frames: event.Frames, SubFrames: event.FractionalFrames}
+
+	return fmt.Sprintf("SMPTEOffset %s at %s", timecode, event.EventContext)
+}
+
+func (event *SequencerSpecific) isEvent() {}
+
+func (event *SequencerSpecific) String() string {
+	return fmt.Sprintf("SequencerSpecific manufacturer % X, data % X at %s", event.ManufacturerID, event.Data, event.EventContext)
+}
+
+func (event *UnknownMeta) isEvent() {}
+
+func (event *UnknownMeta) String() string {
+	return fmt.Sprintf("UnknownMeta type 0x%02X, data % X at %s", event.MetaType, event.Data, event.EventContext)
+}
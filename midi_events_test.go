@@ -0,0 +1,91 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for events.
+ */
+
+package midi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Events should describe themselves.
+func TestEventString(t *testing.T) {
+	var context = EventContext{Track: 1, DeltaTicks: 10, AbsoluteTicks: 96}
+
+	assertStringsEqual((&NoteOn{EventContext: context, Channel: 2, Pitch: 60, Velocity: 64}).String(), "NoteOn channel 2, pitch 60, velocity 64 at track 1, tick 96 (+10)", t)
+	assertStringsEqual((&SequenceName{EventContext: context, Text: "Pian"}).String(), `SequenceName "Pian" at track 1, tick 96 (+10)`, t)
+	assertStringsEqual((&SysEx{EventContext: context, Data: []byte{0x7E, 0xF7}}).String(), "SysEx 7E F7 at track 1, tick 96 (+10)", t)
+	assertStringsEqual((&TimeSignature{EventContext: context, Numerator: 6, Denominator: 3, ClocksPerClick: 36, DemiSemiQuaverPerQuarter: 8}).String(), "TimeSignature 6/8, 36 clocks per click, 8 demisemiquavers per quarter at track 1, tick 96 (+10)", t)
+	assertStringsEqual((&EndOfTrack{EventContext: context}).String(), "EndOfTrack at track 1, tick 96 (+10)", t)
+	assertStringsEqual((&SMPTEOffset{EventContext: context, FrameRate: SMPTE25, Hours: 1, Minutes: 2, Seconds: 3, Frames: 4, FractionalFrames: 5}).String(), "SMPTEOffset 01:02:03:04.05 at track 1, tick 96 (+10)", t)
+}
+
+// Channel events should have a channel and others shouldn't.
+func TestChannelEvent(t *testing.T) {
+	var events = []Event{&NoteOn{Channel: 3}, &Tempo{}, &PitchWheel{Channel: 4}, &SysEx{}}
+
+	channelEvent, ok := events[0].(ChannelEvent)
+	assertTrue(ok, t)
+	assertUint8sEqual(channelEvent.ChannelNumber(), 3, t)
+
+	_, ok = events[1].(ChannelEvent)
+	assertFalse(ok, t)
+
+	channelEvent, ok = events[2].(ChannelEvent)
+	assertTrue(ok, t)
+	assertUint8sEqual(channelEvent.ChannelNumber(), 4, t)
+
+	_, ok = events[3].(ChannelEvent)
+	assertFalse(ok, t)
+}
+
+// Sorting should be by time, then track, and otherwise stable.
+func TestSortEvents(t *testing.T) {
+	var events = []Event{
+		&NoteOn{EventContext: EventContext{Track: 1, AbsoluteTicks: 10}, Pitch: 1},
+		&NoteOn{EventContext: EventContext{Track: 1, AbsoluteTicks: 0}, Pitch: 2},
+		&NoteOff{EventContext: EventContext{Track: 1, AbsoluteTicks: 10}, Pitch: 3},
+		&Tempo{EventContext: EventContext{Track: 0, AbsoluteTicks: 10}},
+	}
+
+	SortEvents(events)
+
+	assertUint8sEqual(events[0].(*NoteOn).Pitch, 2, t)
+	_, ok := events[1].(*Tempo)
+	assertTrue(ok, t)
+	assertUint8sEqual(events[2].(*NoteOn).Pitch, 1, t)
+	assertUint8sEqual(events[3].(*NoteOff).Pitch, 3, t)
+}
+
+// Events should be equal by value.
+func TestEventsEqual(t *testing.T) {
+	var context = EventContext{Track: 1, AbsoluteTicks: 10}
+
+	assertTrue(EventsEqual(&NoteOn{EventContext: context, Pitch: 1}, &NoteOn{EventContext: context, Pitch: 1}), t)
+	assertFalse(EventsEqual(&NoteOn{EventContext: context, Pitch: 1}, &NoteOff{EventContext: context, Pitch: 1}), t)
+	assertFalse(EventsEqual(&NoteOn{EventContext: context, Pitch: 1}, &NoteOn{Pitch: 1}), t)
+	assertTrue(EventsEqual(&SysEx{Data: []byte{1, 2}}, &SysEx{Data: []byte{1, 2}}), t)
+	assertFalse(EventsEqual(&SysEx{Data: []byte{1, 2}}, &SysEx{Data: []byte{1, 3}}), t)
+}
+
+// Events should serialize and come back the same.
+func TestEventJSON(t *testing.T) {
+	var event = &KeySignature{EventContext: EventContext{Track: 2, DeltaTicks: 5, AbsoluteTicks: 100, Offset: 30}, Key: DegreeG, Mode: MajorMode, SharpsOrFlats: 1}
+
+	data, err := json.Marshal(event)
+	assertNoError(err, t)
+
+	var result = new(KeySignature)
+	assertNoError(json.Unmarshal(data, result), t)
+	assertTrue(EventsEqual(event, result), t)
+}
@@ -0,0 +1,90 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * The file.
+ * A whole MIDI file in memory, for code that wants to look at or change all of it.
+ */
+
+package midi
+
+import (
+	"io"
+)
+
+// File is a whole Standard Midi File.
+type File struct {
+	// The SMF header, as found in the file.
+	HeaderData
+
+	Tracks []Track
+
+	// Chunks that aren't MThd or MTrk, so that they can be written back.
+	UnknownChunks []UnknownChunk
+}
+
+// UnknownChunk is a chunk of a type that isn't understood, with its data.
+type UnknownChunk struct {
+	ChunkType string
+	Data      []byte
+
+	// The index of the Track that it comes before in the file, or len(Tracks) if it comes after them all.
+	BeforeTrack int
+}
+
+// Track is the events of an MTrk chunk, in the order found in the file.
+// Each event's context has the index of the Track, and its absolute time in ticks from the start of the track.
+type Track struct {
+	Events []Event
+}
+
+// ReadFile reads a whole Standard Midi File.
+// If the File is written back unchanged with WriteFile the bytes are the same, unless the file was malformed,
+// or had lengths written with more bytes than needed.
+func ReadFile(input io.Reader) (*File, error) {
+	return readFile(NewDecoder(input))
+}
+
+// ReadFileFromBytes reads a whole Standard Midi File from MIDI data in memory.
+func ReadFileFromBytes(data []byte) (*File, error) {
+	return readFile(NewDecoderFromBytes(data))
+}
+
+func readFile(decoder *Decoder) (*File, error) {
+	header, err := decoder.Header()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file = &File{HeaderData: header}
+
+	for event, err := range decoder.Events() {
+		if err != nil {
+			return nil, err
+		}
+
+		var track = event.context().Track
+
+		for len(file.Tracks) <= track {
+			file.Tracks = append(file.Tracks, Track{})
+		}
+
+		file.Tracks[track].Events = append(file.Tracks[track].Events, event)
+	}
+
+	// Empty tracks at the end have no events to say they're there.
+	for len(file.Tracks) < decoder.lexer.tracks {
+		file.Tracks = append(file.Tracks, Track{})
+	}
+
+	file.UnknownChunks = decoder.UnknownChunks()
+
+	return file, nil
+}
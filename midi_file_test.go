@@ -0,0 +1,81 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the file.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// A file should be read into tracks of events.
+func TestReadFile(t *testing.T) {
+	file, err := ReadFile(bytes.NewReader(testMidiFile()))
+	assertNoError(err, t)
+
+	assertUint16Equal(file.Format, SimultaneousTracks, t)
+	assertUint16Equal(file.TicksPerQuarterNote, 96, t)
+	assertIntsEqual(len(file.Tracks), 2, t)
+
+	assertIntsEqual(len(file.Tracks[0].Events), 3, t)
+	assertIntsEqual(len(file.Tracks[1].Events), 7, t)
+
+	for i, track := range file.Tracks {
+		for _, event := range track.Events {
+			assertIntsEqual(event.Context().Track, i, t)
+		}
+	}
+
+	noteOff := file.Tracks[1].Events[5].(*NoteOff)
+	assertUint8sEqual(noteOff.Pitch, 0x40, t)
+	assertIntsEqual(int(noteOff.AbsoluteTicks), 0x60, t)
+
+	_, ok := file.Tracks[1].Events[6].(*EndOfTrack)
+	assertTrue(ok, t)
+}
+
+// Empty tracks, which are only allowed in LenientMode, should still be there.
+func TestReadFileEmptyTrack(t *testing.T) {
+	var data = []byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x60,
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, 0x00, 0xFF, 0x2F, 0x00,
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	var decoder = NewDecoderFromBytes(data)
+	decoder.SetMode(LenientMode)
+
+	file, err := readFile(decoder)
+	assertNoError(err, t)
+	assertIntsEqual(len(file.Tracks), 2, t)
+	assertIntsEqual(len(file.Tracks[0].Events), 1, t)
+	assertIntsEqual(len(file.Tracks[1].Events), 0, t)
+}
+
+// Errors should be returned as LexErrors.
+func TestReadFileError(t *testing.T) {
+	var data = testMidiFile()
+
+	file, err := ReadFileFromBytes(data[:len(data)-3])
+
+	if file != nil {
+		t.Fatal("Expected no file")
+	}
+
+	assertTrue(errors.Is(err, UnexpectedEndOfFile), t)
+
+	var lexError LexError
+	assertTrue(errors.As(err, &lexError), t)
+	assertIntsEqual(lexError.Event, 6, t)
+}
@@ -16,16 +16,36 @@ package midi
 import (
 	// "fmt"
 	"io"
+	"slices"
 )
 
-// parseUint32 parse a 4-byte 32 bit integer from a ReadSeeker.
-// It returns the 32-bit value and an error.
-func parseUint32(reader io.ReadSeeker) (uint32, error) {
-	var buffer []byte = make([]byte, 4)
+// readBigEndian reads a big-endian integer of up to 4 bytes from a ReadSeeker.
+// If the reader is also a ByteReader it's read a byte at a time, which doesn't allocate.
+// Otherwise it's read with a single Read into a new buffer.
+// It returns the value and an error.
+func readBigEndian(reader io.ReadSeeker, length int) (uint32, error) {
+	var value uint32 = 0x00
+
+	if byteReader, ok := reader.(io.ByteReader); ok {
+		for i := 0; i < length; i++ {
+			octet, err := byteReader.ReadByte()
+
+			// If we couldn't read a byte, that's a problem.
+			if err != nil {
+				return 0, UnexpectedEndOfFile
+			}
+
+			value = value<<8 | uint32(octet)
+		}
+
+		return value, nil
+	}
+
+	var buffer []byte = make([]byte, length)
 	num, err := reader.Read(buffer)
 
-	// If we couldn't read 4 bytes, that's a problem.
-	if num != 4 {
+	// If we couldn't read enough bytes, that's a problem.
+	if num != length {
 		return 0, UnexpectedEndOfFile
 	}
 
@@ -34,142 +54,83 @@ func parseUint32(reader io.ReadSeeker) (uint32, error) {
 		return 0, err
 	}
 
-	var value uint32 = 0x00
-	value |= uint32(buffer[3]) << 0
-	value |= uint32(buffer[2]) << 8
-	value |= uint32(buffer[1]) << 16
-	value |= uint32(buffer[0]) << 24
+	for i := 0; i < length; i++ {
+		value = value<<8 | uint32(buffer[i])
+	}
 
 	return value, nil
 }
 
+// parseUint32 parse a 4-byte 32 bit integer from a ReadSeeker.
+// It returns the 32-bit value and an error.
+func parseUint32(reader io.ReadSeeker) (uint32, error) {
+	return readBigEndian(reader, 4)
+}
+
 // parseUint24 parse a 3-byte 24 bit integer from a ReadSeeker.
 // It returns the 32-bit value and an error.
 // TODO TEST
 func parseUint24(reader io.ReadSeeker) (uint32, error) {
-	var buffer []byte = make([]byte, 3)
-	num, err := reader.Read(buffer)
+	return readBigEndian(reader, 3)
+}
 
-	// If we couldn't read 3 bytes, that's a problem.
-	if num != 3 {
-		return 0, UnexpectedEndOfFile
-	}
+// decodeUint24 decodes a 3-byte 24 bit integer from the start of a byte slice, e.g. meta event data.
+func decodeUint24(data []byte) uint32 {
+	var value uint32 = 0x00
+	value |= uint32(data[2]) << 0
+	value |= uint32(data[1]) << 8
+	value |= uint32(data[0]) << 16
 
-	// If there was some other problem, that's also a problem.
-	if err != nil {
-		return 0, err
-	}
+	return value
+}
 
-	var value uint32 = 0x00
-	value |= uint32(buffer[2]) << 0
-	value |= uint32(buffer[1]) << 8
-	value |= uint32(buffer[0]) << 16
+// decodeUint16 decodes a 2-byte 16 bit integer from the start of a byte slice, e.g. meta event data.
+func decodeUint16(data []byte) uint16 {
+	var value uint16 = 0x00
+	value |= uint16(data[1]) << 0
+	value |= uint16(data[0]) << 8
 
-	return value, nil
+	return value
 }
 
 // parseUint16 parses a 2-byte 16 bit integer from a ReadSeeker.
 // It returns the 16-bit value and an error.
 func parseUint16(reader io.ReadSeeker) (uint16, error) {
+	value, err := readBigEndian(reader, 2)
 
-	var buffer []byte = make([]byte, 2)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 2 bytes, that's a problem.
-	if num != 2 {
-		return 0, UnexpectedEndOfFile
-	}
-
-	// If there was some other problem, that's also a problem.
-	if err != nil {
-		return 0, err
-	}
-
-	var value uint16 = 0x00
-	value |= uint16(buffer[1]) << 0
-	value |= uint16(buffer[0]) << 8
-
-	return value, nil
+	return uint16(value), err
 }
 
 // parseUint7 parses a 7-bit bit integer from a ReadSeeker, ignoring the high bit.
 // It returns the 8-bit value and an error.
 func parseUint7(reader io.ReadSeeker) (uint8, error) {
+	value, err := readBigEndian(reader, 1)
 
-	var buffer []byte = make([]byte, 1)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 1 bytes, that's a problem.
-	if num != 1 {
-		return 0, UnexpectedEndOfFile
-	}
-
-	// If there was some other problem, that's also a problem.
-	if err != nil {
-		return 0, err
-	}
-
-	return (buffer[0] & 0x7f), nil
+	return uint8(value) & 0x7f, err
 }
 
 // parseTwoUint7 parses two 7-bit bit integer stored in consecutive bytes from a ReadSeeker, ignoring the high bit in each.
 // It returns the 8-bit value and an error.
 func parseTwoUint7(reader io.ReadSeeker) (uint8, uint8, error) {
+	value, err := readBigEndian(reader, 2)
 
-	var buffer []byte = make([]byte, 2)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 2 bytes, that's a problem.
-	if num != 2 {
-		return 0, 0, UnexpectedEndOfFile
-	}
-
-	// If there was some other problem, that's also a problem.
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return (buffer[0] & 0x7f), (buffer[1] & 0x7f), nil
+	return uint8(value>>8) & 0x7f, uint8(value) & 0x7f, err
 }
 
 // parseUint8 parses an 8-bit bit integer stored in a bytes from a ReadSeeker.
 // It returns a single uint8.
 func parseUint8(reader io.ReadSeeker) (uint8, error) {
+	value, err := readBigEndian(reader, 1)
 
-	var buffer []byte = make([]byte, 1)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 1 bytes, that's a problem.
-	if num != 1 {
-		return 0, UnexpectedEndOfFile
-	}
-
-	// If there was some other problem, that's also a problem.
-	if err != nil {
-		return 0, err
-	}
-
-	return uint8(buffer[0]), nil
+	return uint8(value), err
 }
 
 // parseInt8 parses an 8-bit bit  signedinteger stored in a bytes from a ReadSeeker.
 // It returns a single int8.
 func parseInt8(reader io.ReadSeeker) (int8, error) {
+	value, err := readBigEndian(reader, 1)
 
-	var buffer []byte = make([]byte, 1)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 1 bytes, that's a problem.
-	if num != 1 {
-		return 0, UnexpectedEndOfFile
-	}
-
-	// If there was some other problem, that's also a problem.
-	if err != nil {
-		return 0, err
-	}
-
-	return int8(buffer[0]), nil
+	return int8(value), err
 }
 
 // parsePitchWheelValue parses a 14-bit signed value, which becomes a signed int16.
@@ -177,59 +138,51 @@ func parseInt8(reader io.ReadSeeker) (int8, error) {
 // Return the signed value relative to the centre, and the absolute value.
 // This is tested in midi_lexer_test.go TestPitchWheel
 func parsePitchWheelValue(reader io.ReadSeeker) (relative int16, absolute uint16, err error) {
+	value, err := readBigEndian(reader, 2)
 
-	var buffer []byte = make([]byte, 2)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 2 bytes, that's a problem.
-	if num != 2 {
-		return 0, 0, UnexpectedEndOfFile
-	}
-
-	// If there was some other problem, that's also a problem.
 	if err != nil {
 		return 0, 0, err
 	}
 
-	var val uint16 = 0
+	relative, absolute = pitchWheelValue(uint8(value>>8), uint8(value))
+
+	return relative, absolute, nil
+}
 
-	val = uint16((buffer[1])&0x7f) << 7
-	val |= uint16(buffer[0]) & 0x7f
-	// fmt.Println(val)
+// pitchWheelValue combines the least and most significant 7 bits of a pitch wheel message.
+// Return the signed value relative to the centre, and the absolute value.
+func pitchWheelValue(leastSignificant uint8, mostSignificant uint8) (relative int16, absolute uint16) {
+	absolute = uint16(mostSignificant&0x7f) << 7
+	absolute |= uint16(leastSignificant) & 0x7f
 
-	// log.Println()
 	// Turn into a signed value relative to the centre.
-	relative = int16(val) - 0x2000
+	relative = int16(absolute) - 0x2000
 
-	return relative, val, nil
+	return
 }
 
 // parseVarLength parses a variable length value from a ReadSeeker.
 // It returns the [up to] 32-bit value and an error.
 func parseVarLength(reader io.ReadSeeker) (uint32, error) {
 
-	// Single byte buffer to read byte by byte.
-	var buffer []byte = make([]uint8, 1)
-
-	// The number of bytes returned.
-	// Should always be 1 unless we reach the EOF
-	var num int = 1
-
 	// Result value
 	var result uint32 = 0x00
 
 	// RTFM.
 	var first = true
-	for (first || (buffer[0]&0x80 == 0x80)) && (num > 0) {
+	var octet uint8 = 0x00
+	for first || (octet&0x80 == 0x80) {
 		result = result << 7
 
-		num, _ = reader.Read(buffer)
-		result |= (uint32(buffer[0]) & 0x7f)
-		first = false
-	}
+		value, err := readBigEndian(reader, 1)
 
-	if num == 0 && !first {
-		return result, UnexpectedEndOfFile
+		if err != nil {
+			return result, UnexpectedEndOfFile
+		}
+
+		octet = uint8(value)
+		result |= (uint32(octet) & 0x7f)
+		first = false
 	}
 
 	return result, nil
@@ -267,6 +220,39 @@ func parseChunkHeader(reader io.ReadSeeker) (ChunkHeader, error) {
 	return chunk, nil
 }
 
+// parseChunkData reads the data of a chunk, given the length from its header.
+func parseChunkData(reader io.ReadSeeker, length uint32) ([]byte, error) {
+	return readData(reader, length)
+}
+
+// How much readData reads at a time.
+const readDataStep = 1 << 16
+
+// readData reads the given number of bytes. The length comes from the file, so rather than trusting it
+// the buffer grows a step at a time as the data arrives, and a short file can't make it allocate much.
+func readData(reader io.Reader, length uint32) ([]byte, error) {
+	var buffer = make([]byte, 0, min(length, readDataStep))
+
+	for uint32(len(buffer)) < length {
+		var step = int(min(length-uint32(len(buffer)), readDataStep))
+		buffer = slices.Grow(buffer, step)
+
+		// Some Readers report the end of the file without an error, so stop when nothing is read.
+		num, err := reader.Read(buffer[len(buffer) : len(buffer)+step])
+		buffer = buffer[:len(buffer)+num]
+
+		if num == 0 {
+			return nil, UnexpectedEndOfFile
+		}
+
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	return buffer, nil
+}
+
 // parseHeaderData parses SMF-header chunk header data.
 // It returns the ChunkHeader struct as a value and an error.
 func parseHeaderData(reader io.ReadSeeker) (HeaderData, error) {
@@ -302,62 +288,61 @@ func parseHeaderData(reader io.ReadSeeker) (HeaderData, error) {
 		headerData.TicksPerQuarterNote = division & 0x7FFF
 		headerData.TimeFormat = MetricalTimeFormat
 	} else {
-		// TODO: Can't be bothered to implement this bit just now.
-		// If you want it, write it!
 		headerData.TimeFormatData = division & 0x7FFF
 		headerData.TimeFormat = TimeCodeTimeFormat
+
+		// "If bit 15 of <division> is a one, delta times in a file correspond to subdivisions of a second"
+		// The upper byte is the negative frame rate, the lower byte the ticks per frame.
+		headerData.FrameRate = SMPTEFrameRate(-int8(division >> 8))
+		headerData.TicksPerFrame = uint8(division & 0xFF)
 	}
 
 	if err != nil {
 		return headerData, err
 	}
 
+	if headerData.TimeFormat == TimeCodeTimeFormat && !validFrameRate(headerData.FrameRate) {
+		return headerData, UnsupportedTimeCodeFormat
+	}
+
 	return headerData, nil
 }
 
 // readStatusByte reads the track event status byte and returns the type and channel
 func readStatusByte(reader io.ReadSeeker) (messageType uint8, messageChannel uint8, err error) {
-	var buffer []byte = make([]byte, 1)
-	num, err := reader.Read(buffer)
-
-	// If we couldn't read 1 byte, that's a problem.
-	if num != 1 {
-		return 0, 0, UnexpectedEndOfFile
-	}
+	value, err := readBigEndian(reader, 1)
 
-	// If there was some other problem, that's also a problem.
 	if err != nil {
 		return 0, 0, err
 	}
 
-	messageType = (buffer[0] & 0xF0) >> 4
-	messageChannel = buffer[0] & 0x0F
+	messageType = (uint8(value) & 0xF0) >> 4
+	messageChannel = uint8(value) & 0x0F
 
 	return
 }
 
+// parseText parses a variable length prefixed string from a ReadSeeker.
 func parseText(reader io.ReadSeeker) (string, error) {
-	length, err := parseVarLength(reader)
+	buffer, err := parseVarLengthData(reader)
 
 	if err != nil {
 		return "", err
 	}
 
-	var buffer []byte = make([]byte, length)
-
-	num, err := reader.Read(buffer)
+	// TODO: Data should be ASCII but might go up to 0xFF.
+	// What will Go do? Try and decode UTF-8?
+	return string(buffer), nil
+}
 
-	// If we couldn't read the entire expected-length buffer, that's a problem.
-	if num != int(length) {
-		return "", UnexpectedEndOfFile
-	}
+// parseVarLengthData parses a variable length value followed by that many bytes of data from a ReadSeeker.
+// It returns the data and an error.
+func parseVarLengthData(reader io.ReadSeeker) ([]byte, error) {
+	length, err := parseVarLength(reader)
 
-	// If there was some other problem, that's also a problem.
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// TODO: Data should be ASCII but might go up to 0xFF.
-	// What will Go do? Try and decode UTF-8?
-	return string(buffer), nil
+	return readData(reader, length)
 }
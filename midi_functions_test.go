@@ -16,6 +16,7 @@ package midi
 
 import (
 	"io"
+	"runtime"
 	"testing"
 )
 
@@ -347,13 +348,15 @@ func TestParseHeaderData(t *testing.T) {
 
 	// Format: 2
 	// Tracks: 1
-	// Division: timecode (actual data ignored for now)
-	var headerTimecode = NewMockReadSeeker(&[]byte{0x00, 0x02, 0x00, 0x01, 0xFF, 0x05})
+	// Division: timecode -25 fps, 40 ticks per frame
+	var headerTimecode = NewMockReadSeeker(&[]byte{0x00, 0x02, 0x00, 0x01, 0xE7, 0x28})
 	expected = HeaderData{
 		Format:              2,
 		NumTracks:           1,
 		TimeFormat:          TimeCodeTimeFormat,
-		TimeFormatData:      0x7F05, // Removed the top timecode type bit flag.
+		TimeFormatData:      0x6728, // Removed the top timecode type bit flag.
+		FrameRate:           SMPTE25,
+		TicksPerFrame:       40,
 		TicksPerQuarterNote: 0}
 
 	data, err = parseHeaderData(headerTimecode)
@@ -366,6 +369,14 @@ func TestParseHeaderData(t *testing.T) {
 		t.Fatal(data, " != ", expected)
 	}
 
+	// Division: timecode -1 fps, which doesn't exist.
+	var badFrameRate = NewMockReadSeeker(&[]byte{0x00, 0x02, 0x00, 0x01, 0xFF, 0x05})
+	data, err = parseHeaderData(badFrameRate)
+
+	if err != UnsupportedTimeCodeFormat {
+		t.Fatal("Expected UnsupportedTimeCodeFormat but got ", err)
+	}
+
 	// Format: 3, which doesn't exist.
 	var badFormat = NewMockReadSeeker(&[]byte{0x00, 0x03, 0x00, 0x01, 0xFF, 0x05})
 	data, err = parseHeaderData(badFormat)
@@ -476,3 +487,27 @@ func TestParseBadString(t *testing.T) {
 
 	assertError(err, UnexpectedEndOfFile, t)
 }
+
+// Test that parseChunkData reads data longer than one step.
+func TestParseChunkData(t *testing.T) {
+	var data = make([]byte, readDataStep*2+10)
+	data[len(data)-1] = 0x42
+
+	result, err := parseChunkData(NewMockReadSeeker(&data), uint32(len(data)))
+
+	assertNoError(err, t)
+	assertBytesEqual(result, data, t)
+}
+
+// Test that parseChunkData signals unexpected early end of file, without allocating the length it was given.
+func TestParseChunkDataTooShort(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	_, err := parseChunkData(NewMockReadSeeker(&[]byte{0x01, 0x02, 0x03}), 0xF0000000)
+
+	runtime.ReadMemStats(&after)
+
+	assertError(err, UnexpectedEndOfFile, t)
+	assertTrue(after.TotalAlloc-before.TotalAlloc < 1<<20, t)
+}
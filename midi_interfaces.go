@@ -24,8 +24,9 @@ type MidiLexerCallback interface {
 	// Finished reading the file.
 	Finished()
 
-	// There was an error when lexing.
-	ErrorReading()
+	// There was an error when lexing. The error is a LexError.
+	// In LenientMode the lexer carries on with the next chunk if it can, otherwise Lex() returns the error.
+	ErrorReading(err error)
 
 	// There was an error opening the file input.
 	ErrorOpeningFile()
@@ -63,13 +64,24 @@ type MidiLexerCallback interface {
 	// TODO remove, duplicated by Finished()
 	Done(time uint32)
 
+	// System Exclusive events
+
+	// A SysEx message (F0). The data is as found in the file, so it ends with 0xF7 unless the message is continued in SysExContinuation packets.
+	SysEx(data []byte, time uint32)
+
+	// A subsequent packet (F7) of a SysEx message divided into several packets. The last packet ends with 0xF7.
+	SysExContinuation(data []byte, time uint32)
+
+	// An escape sequence (F7) outside a SysEx message. The data is to be sent as-is, e.g. System Real-Time messages.
+	EscapeSequence(data []byte, time uint32)
+
 	// Meta Events
 
 	SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32)
 	Text(channel uint8, text string, time uint32)
 
 	// The Key and Mode. Also the sharps (>0) or flats (<0) as per MIDI spec, in case you want to use it.
-	KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8)
+	KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32)
 	CopyrightText(channel uint8, text string, time uint32)
 	SequenceName(channel uint8, text string, time uint32)
 	TrackInstrumentName(channel uint8, text string, time uint32)
@@ -77,5 +89,141 @@ type MidiLexerCallback interface {
 	MarkerText(channel uint8, text string, time uint32)
 	CuePointText(channel uint8, text string, time uint32)
 	EndOfTrack(channel uint8, time uint32)
+
+	// The obsolete MIDI Channel Prefix, the channel that following meta and SysEx events apply to.
+	ChannelPrefix(channel uint8, time uint32)
+
+	// The obsolete MIDI Port, the output port that the track's events are for.
+	MidiPort(port uint8, time uint32)
 	TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32)
+
+	// The SMPTE time at which the track starts. Fractional frames are in 100ths of a frame.
+	SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32)
+
+	// Sequencer specific meta event. The manufacturer ID is one byte, or three if the first is zero.
+	SequencerSpecific(manufacturerID []byte, data []byte, time uint32)
+
+	// A meta event of a type that isn't otherwise understood, or too short to be, with its data.
+	UnknownMeta(metaType uint8, data []byte, time uint32)
+}
+
+// MidiLexerContextCallback may also be implemented by a MidiLexerCallback.
+// If so, BeforeEvent is called with the position of each track event, before the event's own method is called.
+// It's called once the status byte is read, before the rest of the event, so if the event turns out to be bad
+// no event method follows it and the next call is ErrorReading.
+type MidiLexerContextCallback interface {
+	BeforeEvent(context EventContext)
+}
+
+// MidiLexerChunkCallback may also be implemented by a MidiLexerCallback.
+// If so, UnknownChunk is called with the data of each chunk that isn't MThd or MTrk, after Track is called with its header.
+// Otherwise unknown chunks are skipped without being read.
+type MidiLexerChunkCallback interface {
+	UnknownChunk(header ChunkHeader, data []byte)
+}
+
+// MidiLexerErrorCallback is like MidiLexerCallback, except that every method returns an error.
+// If a method returns an error the lexer stops and Lex() returns it, wrapped in a LexError.
+// Pass it to the MidiLexer using ErrorCallback().
+type MidiLexerErrorCallback interface {
+	// Meta messages
+
+	// Started reading a file.
+	Began() error
+
+	// Finished reading the file.
+	Finished() error
+
+	// There was an error when lexing. The error is a LexError.
+	// In LenientMode the lexer carries on with the next chunk if it can, otherwise Lex() returns the error.
+	// Returning an error stops the lexer, even in LenientMode.
+	ErrorReading(err error) error
+
+	// There was an error opening the file input.
+	ErrorOpeningFile() error
+
+	// SMF header.
+	Header(header HeaderData) error
+
+	// A chunk header (usually MTrk).
+	Track(header ChunkHeader) error
+
+	// Midi in-track messages
+	NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) error
+	NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32) error
+	PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) error
+	ControlChange(channel uint8, controller uint8, value uint8, time uint32) error
+	ProgramChange(channel uint8, program uint8, time uint32) error
+	ChannelAfterTouch(channel uint8, value uint8, time uint32) error
+	PitchWheel(channel uint8, value int16, absValue uint16, time uint32) error
+	TimeCodeQuarter(messageType uint8, values uint8, time uint32) error
+	SongPositionPointer(beats uint16, time uint32) error
+	SongSelect(song uint8, time uint32) error
+	Undefined1(time uint32) error
+	Undefined2(time uint32) error
+	TuneRequest(time uint32) error
+	TimingClock(time uint32) error
+	Undefined3(time uint32) error
+	Start(time uint32) error
+	Continue(time uint32) error
+	Stop(time uint32) error
+	Undefined4(time uint32) error
+	Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32) error
+	ActiveSensing(time uint32) error
+	Reset(time uint32) error
+
+	// TODO remove, duplicated by Finished()
+	Done(time uint32) error
+
+	// System Exclusive events
+
+	// A SysEx message (F0). The data is as found in the file, so it ends with 0xF7 unless the message is continued in SysExContinuation packets.
+	SysEx(data []byte, time uint32) error
+
+	// A subsequent packet (F7) of a SysEx message divided into several packets. The last packet ends with 0xF7.
+	SysExContinuation(data []byte, time uint32) error
+
+	// An escape sequence (F7) outside a SysEx message. The data is to be sent as-is, e.g. System Real-Time messages.
+	EscapeSequence(data []byte, time uint32) error
+
+	// Meta Events
+
+	SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) error
+	Text(channel uint8, text string, time uint32) error
+
+	// The Key and Mode. Also the sharps (>0) or flats (<0) as per MIDI spec, in case you want to use it.
+	KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) error
+	CopyrightText(channel uint8, text string, time uint32) error
+	SequenceName(channel uint8, text string, time uint32) error
+	TrackInstrumentName(channel uint8, text string, time uint32) error
+	LyricText(channel uint8, text string, time uint32) error
+	MarkerText(channel uint8, text string, time uint32) error
+	CuePointText(channel uint8, text string, time uint32) error
+	EndOfTrack(channel uint8, time uint32) error
+
+	// The obsolete MIDI Channel Prefix, the channel that following meta and SysEx events apply to.
+	ChannelPrefix(channel uint8, time uint32) error
+
+	// The obsolete MIDI Port, the output port that the track's events are for.
+	MidiPort(port uint8, time uint32) error
+	TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) error
+
+	// The SMPTE time at which the track starts. Fractional frames are in 100ths of a frame.
+	SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) error
+
+	// Sequencer specific meta event. The manufacturer ID is one byte, or three if the first is zero.
+	SequencerSpecific(manufacturerID []byte, data []byte, time uint32) error
+
+	// A meta event of a type that isn't otherwise understood, or too short to be, with its data.
+	UnknownMeta(metaType uint8, data []byte, time uint32) error
+}
+
+// MidiLexerErrorContextCallback may also be implemented by a MidiLexerErrorCallback, like MidiLexerContextCallback.
+type MidiLexerErrorContextCallback interface {
+	BeforeEvent(context EventContext) error
+}
+
+// MidiLexerErrorChunkCallback may also be implemented by a MidiLexerErrorCallback, like MidiLexerChunkCallback.
+type MidiLexerErrorChunkCallback interface {
+	UnknownChunk(header ChunkHeader, data []byte) error
 }
@@ -15,6 +15,7 @@
 package midi
 
 import (
+	"errors"
 	"io"
 	"testing"
 )
@@ -108,6 +109,27 @@ func TestMidiLexerShouldSkipUnknownTrack(t *testing.T) {
 	assertIntsEqual(int(position), 10, t)
 }
 
+// Expect a chunk part way through the file, get an unrecognised type. Should skip to next.
+// ExpectChunk -> ExpectChunk
+func TestMidiLexerShouldSkipUnknownTrackAfterStart(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{ /* Something already read */ 0x00, 0x00, 0x00, 0x00 /* start of unknown block, claims to be 2-long */, 0xDE, 0xAD, 0xBE, 0xEF, 0x00, 0x00, 0x00, 0x02, 0xCA, 0xFE /* Start of next block. */, 0x4D, 0x54, 0x72, 0x6B})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.input.Seek(4, 0)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	// Reader should have jumped to position 14, the next block.
+	var position, err = lexer.input.Seek(0, 1)
+	assertNoError(err, t)
+	assertIntsEqual(int(position), 14, t)
+}
+
 // Expect a chunk, get an end of file. Should end gracefully.
 // ExpectChunk -> Done
 func TestMidiLexerShouldReachEndOfFile(t *testing.T) {
@@ -168,14 +190,54 @@ func TestMidiLexerShouldExpectTrackEvent(t *testing.T) {
 // Should store reported track length and go back to ExpectChunk at end of chunk.
 // ExpectChunk -> ExpectTrackEvent
 func TestMidiLexerShouldHandleChunkLengths(t *testing.T) {
-	// TODO
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x08, 0x00, 0x90, 0x3C, 0x40, 0x00, 0xFF, 0x2F, 0x00})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(int(lexer.nextChunkHeader), 16, t)
+	assertIntsEqual(lexer.state, ExpectTrackEvent, t)
+
+	// NoteOn
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(lexer.state, ExpectTrackEvent, t)
+
+	// EndOfTrack, exactly at the end of the chunk.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(lexer.state, ExpectChunk, t)
 }
 
 // Expect a chunk, get MTrk with a too-short length.
 // Should raise a BadSizeChunk error
 // ExpectChunk -> ExpectTrackEvent
 func TestMidiLexerShouldHandleChunkLengthError(t *testing.T) {
-	// TODO
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x06, 0x00, 0x90, 0x3C, 0x40, 0x00, 0xFF, 0x2F, 0x00})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	// NoteOn
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	// EndOfTrack starts inside the chunk but ends outside it.
+	finished, err = lexer.next()
+	assertError(err, BadSizeChunk, t)
 }
 
 // Expect a track event, parse a NoteOff message.
@@ -670,21 +732,879 @@ func TestEndOfTrack(t *testing.T) {
 	assertUint32Equal(mockLexerCallback.time, 0x09, t)
 }
 
-/*
- * Exceptional state transitions. 
- */
+// Expect track events, get a NoteOn followed by two more using running status.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestRunningStatus(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x10, 0x93, 0x3C, 0x40, // NoteOn channel 3 with status
+		0x20, 0x3E, 0x41, // NoteOn with running status
+		0x30, 0x40, 0x00}) // NoteOn with running status
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
 
-// Bad header chunk type at start of file should result in error
-func TestLexerShouldErrorBadHeader(t *testing.T) {
+	// Pre: ExpectChunk
+	// Should be ready for a track event.
+	lexer.state = ExpectTrackEvent
 
-	// Just enough for the header chunk
+	for i := 0; i < 3; i++ {
+		finished, err = lexer.next()
+		assertNoError(err, t)
+		assertFalse(finished, t)
+	}
+
+	assertIntsEqual(lexer.state, ExpectTrackEvent, t)
+
+	// All three should have been NoteOns with the last values.
+	assertIntsEqual(mockLexerCallback.noteOn, 3, t)
+	assertUint32Equal(mockLexerCallback.time, 0x30, t)
+	assertUint8sEqual(mockLexerCallback.channel, 0x03, t)
+	assertUint8sEqual(mockLexerCallback.pitch, 0x40, t)
+	assertUint8sEqual(mockLexerCallback.velocity, 0x00, t)
+
+	// The context should say that running status was used.
+	assertTrue(mockLexerCallback.context.RunningStatus, t)
+
+	// Everything should have been consumed.
+	var position, err = lexer.input.Seek(0, 1)
+	assertNoError(err, t)
+	assertIntsEqual(int(position), 10, t)
+}
+
+// Running status should apply to single data byte messages too.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestRunningStatusChannelPressure(t *testing.T) {
 	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x10, 0xD2, 0x12, // Channel pressure with status
+		0x20, 0x34}) // Channel pressure with running status
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
 
-	mockReadSeeker = NewMockReadSeeker(&[]byte{0xDE, 0xAD, 0xBE, 0xEF, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0xC8})
+	lexer.state = ExpectTrackEvent
+
+	for i := 0; i < 2; i++ {
+		finished, err = lexer.next()
+		assertNoError(err, t)
+		assertFalse(finished, t)
+	}
 
+	assertIntsEqual(mockLexerCallback.channelAfterTouch, 2, t)
+	assertUint32Equal(mockLexerCallback.time, 0x20, t)
+	assertUint8sEqual(mockLexerCallback.channel, 0x02, t)
+	assertUint8sEqual(mockLexerCallback.pressure, 0x34, t)
+}
+
+// Expect a track event, get a complete SysEx message.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestSysEx(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x09, 0xF0, 0x05, 0x7E, 0x7F, 0x09, 0x01, 0xF7, // GM System On
+		0x00, 0x90, 0x3C, 0x40})
 	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
 
+	lexer.state = ExpectTrackEvent
+
 	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertFalse(finished, t)
 
-	assertError(err, ExpectedMthd, t)
+	assertIntsEqual(mockLexerCallback.sysEx, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x09, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x7E, 0x7F, 0x09, 0x01, 0xF7}, t)
+
+	// The payload should have been consumed so the next event is read correctly.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 1, t)
+	assertUint8sEqual(mockLexerCallback.pitch, 0x3C, t)
+}
+
+// Expect track events, get a SysEx message divided into packets.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestDividedSysEx(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x00, 0xF0, 0x03, 0x43, 0x12, 0x00, // First packet, no terminating F7
+		0x10, 0xF7, 0x02, 0x43, 0x12, // Continuation
+		0x20, 0xF7, 0x02, 0x00, 0xF7, // Final continuation
+		0x30, 0xF7, 0x01, 0xF8}) // Escape sequence
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.sysEx, 1, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x43, 0x12, 0x00}, t)
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.sysExContinuation, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x10, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x43, 0x12}, t)
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.sysExContinuation, 2, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x00, 0xF7}, t)
+
+	// Once the SysEx message is terminated an F7 event is an escape.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.sysExContinuation, 2, t)
+	assertIntsEqual(mockLexerCallback.escapeSequence, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x30, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0xF8}, t)
+}
+
+// Expect a track event, get a SequencerSpecific event with a one byte manufacturer ID.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestSequencerSpecific(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x09, 0xFF, 0x7F, 0x04, 0x43, 0x01, 0x02, 0x03,
+		0x00, 0x90, 0x3C, 0x40})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertFalse(finished, t)
+
+	assertIntsEqual(mockLexerCallback.sequencerSpecific, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x09, t)
+	assertBytesEqual(mockLexerCallback.manufacturerID, []byte{0x43}, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x01, 0x02, 0x03}, t)
+
+	// The next event should be read correctly.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 1, t)
+}
+
+// Expect a track event, get a SequencerSpecific event with a three byte manufacturer ID.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestSequencerSpecificExtendedID(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x09, 0xFF, 0x7F, 0x04, 0x00, 0x00, 0x41, 0x05})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	assertIntsEqual(mockLexerCallback.sequencerSpecific, 1, t)
+	assertBytesEqual(mockLexerCallback.manufacturerID, []byte{0x00, 0x00, 0x41}, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x05}, t)
+}
+
+// Expect a track event, get a SequencerSpecific event too short for its manufacturer ID.
+// It should be passed on as an unknown meta event, or be an error in StrictMode.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestSequencerSpecificTooShort(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x09, 0xFF, 0x7F, 0x00,
+		0x01, 0xFF, 0x7F, 0x02, 0x00, 0x00,
+		0x00, 0x90, 0x3C, 0x40})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.sequencerSpecific, 0, t)
+	assertIntsEqual(mockLexerCallback.unknownMeta, 1, t)
+	assertUint8sEqual(mockLexerCallback.metaType, 0x7F, t)
+	assertUint32Equal(mockLexerCallback.time, 0x09, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{}, t)
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.unknownMeta, 2, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x00, 0x00}, t)
+
+	// The next event should be read correctly.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 1, t)
+
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x09, 0xFF, 0x7F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	var lengthError, ok = err.(UnexpectedEventLengthError)
+	assertTrue(ok, t)
+	assertStringsEqual(lengthError.message, "SequencerSpecific too short for manufacturer ID", t)
+	assertIntsEqual(mockLexerCallback.unknownMeta, 0, t)
+}
+
+// Expect a track event, get meta and SysEx events longer than what's left of the track chunk.
+// They shouldn't be read.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestTrackDataTooLong(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x00, 0xFF, 0x01, 0xFF, 0xFF, 0xFF, 0x7F, 0x61, 0x62},
+		{0x00, 0xF0, 0xFF, 0xFF, 0xFF, 0x7F, 0x01, 0x02, 0x03},
+		{0x00, 0xFF, 0x01, 0x06, 0x61, 0x62, 0x63, 0x64, 0x65},
+	} {
+		mockLexerCallback = new(CountingLexerCallback)
+		mockReadSeeker = NewMockReadSeeker(&data)
+		lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+		lexer.state = ExpectTrackEvent
+		lexer.nextChunkHeader = int64(len(data))
+
+		finished, err = lexer.next()
+		assertError(err, BadSizeChunk, t)
+		assertIntsEqual(mockLexerCallback.text, 0, t)
+		assertIntsEqual(mockLexerCallback.sysEx, 0, t)
+	}
+
+	// Data that fills the rest of the chunk is fine.
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x00, 0xFF, 0x01, 0x05, 0x61, 0x62, 0x63, 0x64, 0x65})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+	lexer.nextChunkHeader = 9
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.text, 1, t)
+}
+
+// Expect a track event, get a meta event of an unknown type.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestUnknownMeta(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x09, 0xFF, 0x60, 0x02, 0xAB, 0xCD,
+		0x00, 0xFF, 0x2F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertFalse(finished, t)
+
+	assertIntsEqual(mockLexerCallback.unknownMeta, 1, t)
+	assertUint8sEqual(mockLexerCallback.metaType, 0x60, t)
+	assertUint32Equal(mockLexerCallback.time, 0x09, t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0xAB, 0xCD}, t)
+
+	// The next event should be read correctly.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.endOfTrack, 1, t)
+	assertIntsEqual(lexer.state, ExpectChunk, t)
+}
+
+// Expect a track event, get a Tempo event.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestTempo(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x09, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	assertIntsEqual(mockLexerCallback.tempo, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x09, t)
+	assertUint32Equal(mockLexerCallback.microsecondsPerCrotchet, 500000, t)
+	assertUint32Equal(mockLexerCallback.bpm, 120, t)
+}
+
+// Expect a track event, get an SMPTEOffset event.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestSMPTEOffset(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+
+	// 01:02:03:04.05 at 25 fps.
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x00, 0xFF, 0x54, 0x05, 0x21, 0x02, 0x03, 0x04, 0x05})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertFalse(finished, t)
+
+	assertIntsEqual(mockLexerCallback.smpteOffset, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x00, t)
+	assertUint8sEqual(uint8(mockLexerCallback.frameRate), SMPTE25, t)
+	assertUint8sEqual(mockLexerCallback.hours, 1, t)
+	assertUint8sEqual(mockLexerCallback.minutes, 2, t)
+	assertUint8sEqual(mockLexerCallback.seconds, 3, t)
+	assertUint8sEqual(mockLexerCallback.frames, 4, t)
+	assertUint8sEqual(mockLexerCallback.fractionalFrames, 5, t)
+
+	// 23 hours at 30 fps drop frame.
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x00, 0xFF, 0x54, 0x05, 0x57, 0x00, 0x00, 0x00, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	assertUint8sEqual(uint8(mockLexerCallback.frameRate), SMPTE30DropFrame, t)
+	assertUint8sEqual(mockLexerCallback.hours, 23, t)
+}
+
+// Expect a track event, get a KeySignature event.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestKeySignature(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+
+	// Two flats, minor.
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x09, 0xFF, 0x59, 0x02, 0xFE, 0x01})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	assertIntsEqual(mockLexerCallback.keySignature, 1, t)
+	assertUint32Equal(mockLexerCallback.time, 0x09, t)
+	assertUint8sEqual(uint8(mockLexerCallback.key), DegreeG, t)
+	assertUint8sEqual(uint8(mockLexerCallback.mode), MinorMode, t)
+	assertIntsEqual(int(mockLexerCallback.sharpsOrFlats), -2, t)
+}
+
+// Each track event should be preceded by its context, with absolute time counted per track.
+func TestEventContext(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x0C, // MTrk, offset 0
+		0x10, 0x90, 0x3C, 0x40, // NoteOn at offset 8
+		0x20, 0x80, 0x3C, 0x40, // NoteOff at offset 12
+		0x00, 0xFF, 0x2F, 0x00, // EndOfTrack at offset 16
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, // MTrk, offset 20
+		0x05, 0xFF, 0x2F, 0x00}) // EndOfTrack at offset 28
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectChunk
+
+	// MTrk
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.beforeEvent, 0, t)
+
+	// NoteOn
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.beforeEvent, 1, t)
+	assertIntsEqual(mockLexerCallback.context.Track, 0, t)
+	assertUint32Equal(mockLexerCallback.context.DeltaTicks, 0x10, t)
+	assertIntsEqual(int(mockLexerCallback.context.AbsoluteTicks), 0x10, t)
+	assertIntsEqual(int(mockLexerCallback.context.Offset), 8, t)
+
+	// NoteOff
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.beforeEvent, 2, t)
+	assertUint32Equal(mockLexerCallback.context.DeltaTicks, 0x20, t)
+	assertIntsEqual(int(mockLexerCallback.context.AbsoluteTicks), 0x30, t)
+	assertIntsEqual(int(mockLexerCallback.context.Offset), 12, t)
+
+	// EndOfTrack
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(int(mockLexerCallback.context.AbsoluteTicks), 0x30, t)
+	assertIntsEqual(int(mockLexerCallback.context.Offset), 16, t)
+
+	// Second MTrk and EndOfTrack, time should start again.
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.beforeEvent, 4, t)
+	assertIntsEqual(mockLexerCallback.context.Track, 1, t)
+	assertIntsEqual(int(mockLexerCallback.context.AbsoluteTicks), 0x05, t)
+	assertIntsEqual(int(mockLexerCallback.context.Offset), 28, t)
+
+	// The lexer should report the same.
+	if lexer.Context() != mockLexerCallback.context {
+		t.Fatal(lexer.Context(), " != ", mockLexerCallback.context)
+	}
+}
+
+// The context is given once the status byte is read, so a bad event gets a context but no event method.
+func TestEventContextBadEvent(t *testing.T) {
+	for _, test := range []struct {
+		data     []byte
+		expected error
+	}{
+		{[]byte{0x10, 0x3C, 0x40}, NoRunningStatus},
+		{[]byte{0x10, 0x90, 0x3C}, UnexpectedEndOfFile},
+	} {
+		mockLexerCallback = new(CountingLexerCallback)
+		mockReadSeeker = NewMockReadSeeker(&test.data)
+		lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+		lexer.state = ExpectTrackEvent
+
+		finished, err = lexer.next()
+		assertError(err, test.expected, t)
+
+		assertIntsEqual(mockLexerCallback.beforeEvent, 1, t)
+		assertUint32Equal(mockLexerCallback.context.DeltaTicks, 0x10, t)
+		assertIntsEqual(int(mockLexerCallback.context.Offset), 0, t)
+		assertIntsEqual(mockLexerCallback.noteOn, 0, t)
+	}
+}
+
+// Expect track events, get the obsolete MIDI Channel Prefix and MIDI Port meta events.
+// ExpectTrackEvent -> ExpectTrackEvent
+func TestChannelPrefixAndMidiPort(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x01, 0xFF, 0x20, 0x01, 0x09, // Channel prefix 9
+		0x02, 0xFF, 0x21, 0x01, 0x03}) // Port 3
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.channelPrefix, 1, t)
+	assertUint8sEqual(mockLexerCallback.channel, 9, t)
+	assertUint32Equal(mockLexerCallback.time, 0x01, t)
+	assertFalse(mockLexerCallback.context.RunningStatus, t)
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.midiPort, 1, t)
+	assertUint8sEqual(mockLexerCallback.port, 3, t)
+	assertUint32Equal(mockLexerCallback.time, 0x02, t)
+}
+
+// Expect a chunk, get an unknown chunk. Its data should be passed to a MidiLexerChunkCallback.
+// ExpectChunk -> ExpectChunk
+func TestUnknownChunkData(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x58, 0x59, 0x5A, 0x5A, 0x00, 0x00, 0x00, 0x03, 0x01, 0x02, 0x03,
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(lexer.state, ExpectChunk, t)
+	assertIntsEqual(mockLexerCallback.unknownChunk, 1, t)
+	assertStringsEqual(mockLexerCallback.chunkHeader.ChunkType, "XYZZ", t)
+	assertBytesEqual(mockLexerCallback.data, []byte{0x01, 0x02, 0x03}, t)
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+	assertIntsEqual(lexer.state, ExpectTrackEvent, t)
+	assertIntsEqual(mockLexerCallback.unknownChunk, 1, t)
+}
+
+// An unknown chunk shorter than its length can't be passed on.
+func TestUnknownChunkDataTooShort(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x58, 0x59, 0x5A, 0x5A, 0x00, 0x00, 0x00, 0x04, 0x01, 0x02, 0x03})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertError(err, UnexpectedEndOfFile, t)
+	assertIntsEqual(mockLexerCallback.unknownChunk, 0, t)
+}
+
+/*
+ * Exceptional state transitions. 
+ */
+
+// Bad header chunk type at start of file should result in error
+func TestLexerShouldErrorBadHeader(t *testing.T) {
+
+	// Just enough for the header chunk
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0xDE, 0xAD, 0xBE, 0xEF, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0xC8})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	finished, err = lexer.next()
+
+	assertError(err, ExpectedMthd, t)
+}
+
+// A data byte with no preceding channel message should result in error.
+func TestRunningStatusWithoutStatus(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x10, 0x3C, 0x40})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+
+	assertError(err, NoRunningStatus, t)
+}
+
+// A meta event should cancel running status.
+func TestRunningStatusCancelledByMetaEvent(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x10, 0x93, 0x3C, 0x40, // NoteOn channel 3 with status
+		0x00, 0xFF, 0x01, 0x01, 0x61, // Text event
+		0x20, 0x3E, 0x41}) // Data bytes with no running status
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	for i := 0; i < 2; i++ {
+		finished, err = lexer.next()
+		assertNoError(err, t)
+	}
+
+	finished, err = lexer.next()
+
+	assertError(err, NoRunningStatus, t)
+}
+
+// A SysEx message longer than the remaining data should result in error.
+func TestSysExTooShort(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x00, 0xF0, 0x05, 0x7E, 0x7F})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+
+	assertError(err, UnexpectedEndOfFile, t)
+	assertIntsEqual(mockLexerCallback.sysEx, 0, t)
+}
+
+// A Tempo event of the wrong length should result in error.
+func TestTempoBadLength(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x09, 0xFF, 0x51, 0x02, 0x07, 0xA1})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+
+	assertError(err, UnexpectedEventLengthError{"Tempo expected length 3"}, t)
+}
+
+// A Tempo event of zero should result in error, not a division by zero.
+func TestTempoZero(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x09, 0xFF, 0x51, 0x03, 0x00, 0x00, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+
+	assertError(err, InvalidTempo, t)
+}
+
+// An SMPTEOffset event of the wrong length should result in error.
+func TestSMPTEOffsetBadLength(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x00, 0xFF, 0x54, 0x04, 0x21, 0x02, 0x03, 0x04})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+
+	assertError(err, UnexpectedEventLengthError{"SMPTEOffset expected length 5"}, t)
+	assertIntsEqual(mockLexerCallback.smpteOffset, 0, t)
+}
+
+// Errors from Lex should say where they happened and wrap the underlying error.
+func TestLexError(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x60, // MThd
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x0B, // MTrk at offset 14
+		0x00, 0x90, 0x3C, 0x40, // NoteOn at offset 22
+		0x00, 0xFF, 0x51, 0x02, 0x07, 0xA1, // Bad tempo at offset 26
+		0x00, 0xFF, 0x2F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	err = lexer.Lex()
+
+	if !errors.Is(err, UnexpectedEventLengthError{"Tempo expected length 3"}) {
+		t.Fatal("Expected UnexpectedEventLengthError got ", err)
+	}
+
+	var lexError LexError
+	if !errors.As(err, &lexError) {
+		t.Fatal("Expected LexError got ", err)
+	}
+
+	assertIntsEqual(int(lexError.Offset), 26, t)
+	assertIntsEqual(lexError.Chunk, 1, t)
+	assertIntsEqual(lexError.Event, 1, t)
+	assertUint8sEqual(lexError.Status, 0xFF, t)
+}
+
+// Errors in chunks should have no event.
+func TestLexErrorInChunk(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x01})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	err = lexer.Lex()
+
+	if !errors.Is(err, UnexpectedEndOfFile) {
+		t.Fatal("Expected UnexpectedEndOfFile got ", err)
+	}
+
+	var lexError LexError
+	if !errors.As(err, &lexError) {
+		t.Fatal("Expected LexError got ", err)
+	}
+
+	assertIntsEqual(int(lexError.Offset), 0, t)
+	assertIntsEqual(lexError.Chunk, 0, t)
+	assertIntsEqual(lexError.Event, -1, t)
+}
+
+// In LenientMode a bad track should be reported and skipped, and the next track lexed.
+func TestLenientModeSkipsBadTrack(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x60, // MThd
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x0A, // MTrk
+		0x00, 0xFF, 0x51, 0x02, 0x07, 0xA1, // Bad tempo
+		0x00, 0xFF, 0x2F, 0x00,
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x08, // MTrk
+		0x00, 0x90, 0x3C, 0x40,
+		0x00, 0xFF, 0x2F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(LenientMode)
+
+	err = lexer.Lex()
+	assertNoError(err, t)
+
+	assertIntsEqual(mockLexerCallback.errorReading, 1, t)
+	if !errors.Is(mockLexerCallback.errorValue, UnexpectedEventLengthError{"Tempo expected length 3"}) {
+		t.Fatal("Expected UnexpectedEventLengthError got ", mockLexerCallback.errorValue)
+	}
+
+	assertIntsEqual(mockLexerCallback.track, 2, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 1, t)
+	assertIntsEqual(mockLexerCallback.endOfTrack, 1, t)
+	assertIntsEqual(mockLexerCallback.finished, 1, t)
+}
+
+// In LenientMode a track without EndOfTrack should be reported, and the next track lexed.
+func TestLenientModeMissingEndOfTrack(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x60, // MThd
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, // MTrk
+		0x00, 0x90, 0x3C, 0x40, // No EndOfTrack
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x08, // MTrk
+		0x00, 0x90, 0x3E, 0x40,
+		0x00, 0xFF, 0x2F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(LenientMode)
+
+	err = lexer.Lex()
+	assertNoError(err, t)
+
+	assertIntsEqual(mockLexerCallback.errorReading, 1, t)
+	if !errors.Is(mockLexerCallback.errorValue, MissingEndOfTrack) {
+		t.Fatal("Expected MissingEndOfTrack got ", mockLexerCallback.errorValue)
+	}
+
+	// The EndOfTrack was expected after the NoteOn.
+	var lexError LexError
+	assertTrue(errors.As(mockLexerCallback.errorValue, &lexError), t)
+	assertIntsEqual(lexError.Chunk, 1, t)
+	assertIntsEqual(lexError.Event, 1, t)
+
+	assertIntsEqual(mockLexerCallback.track, 2, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 2, t)
+	assertUint8sEqual(mockLexerCallback.pitch, 0x3E, t)
+	assertIntsEqual(mockLexerCallback.finished, 1, t)
+}
+
+// Without LenientMode an error should be reported and stop the lexer.
+func TestErrorReadingWithoutLenientMode(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x60, // MThd
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x0A, // MTrk
+		0x00, 0xFF, 0x51, 0x02, 0x07, 0xA1, // Bad tempo
+		0x00, 0xFF, 0x2F, 0x00,
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x08, // MTrk
+		0x00, 0x90, 0x3C, 0x40,
+		0x00, 0xFF, 0x2F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	err = lexer.Lex()
+
+	if !errors.Is(err, UnexpectedEventLengthError{"Tempo expected length 3"}) {
+		t.Fatal("Expected UnexpectedEventLengthError got ", err)
+	}
+
+	assertIntsEqual(mockLexerCallback.errorReading, 1, t)
+	assertIntsEqual(mockLexerCallback.track, 1, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 0, t)
+	assertIntsEqual(mockLexerCallback.finished, 0, t)
+}
+
+// In StrictMode a track with data after EndOfTrack should be an error.
+func TestStrictModeDataAfterEndOfTrack(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x08, 0x00, 0xFF, 0x2F, 0x00, 0x00, 0x90, 0x3C, 0x40})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	finished, err = lexer.next()
+	assertTrue(errors.Is(err, EndOfTrackNotLast), t)
+	assertIntsEqual(mockLexerCallback.endOfTrack, 0, t)
+}
+
+// In StrictMode an EndOfTrack that runs past the end of the chunk should be an error.
+func TestStrictModeEndOfTrackPastChunk(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x03, 0x00, 0xFF, 0x2F, 0x00})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	finished, err = lexer.next()
+	assertTrue(errors.Is(err, BadSizeChunk), t)
+}
+
+// In StrictMode a track without EndOfTrack should be an error.
+func TestStrictModeMissingEndOfTrack(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, 0x00, 0x90, 0x3C, 0x40, 0x4D, 0x54, 0x72, 0x6B})
+
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectChunk
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	finished, err = lexer.next()
+	assertNoError(err, t)
+
+	finished, err = lexer.next()
+	assertError(err, MissingEndOfTrack, t)
+
+	// The EndOfTrack was expected after the NoteOn.
+	assertIntsEqual(lexer.lexError(err).Event, 1, t)
+}
+
+// In StrictMode the number of tracks should match the header.
+func TestStrictModeWrongNumberOfTracks(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x60, // MThd, 2 tracks
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, // MTrk
+		0x00, 0xFF, 0x2F, 0x00})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+
+	// Fine without StrictMode.
+	err = lexer.Lex()
+	assertNoError(err, t)
+
+	mockReadSeeker.Seek(0, 0)
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	err = lexer.Lex()
+
+	if !errors.Is(err, WrongNumberOfTracks) {
+		t.Fatal("Expected WrongNumberOfTracks got ", err)
+	}
+}
+
+// In StrictMode format 0 files should have one track.
+func TestStrictModeFormat0(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x02, 0x00, 0x60})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	finished, err = lexer.next()
+
+	assertError(err, Format0NotSingleTrack, t)
+}
+
+// In StrictMode the header chunk should be 6 long, otherwise extra data should be skipped.
+func TestHeaderChunkLength(t *testing.T) {
+	var data = []byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00, 0x60, 0x00, 0x00, // MThd, 8 long
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x04, // MTrk
+		0x00, 0xFF, 0x2F, 0x00}
+
+	mockLexerCallback = new(CountingLexerCallback)
+	lexer = NewMidiLexer(NewMockReadSeeker(&data), mockLexerCallback)
+
+	err = lexer.Lex()
+	assertNoError(err, t)
+	assertIntsEqual(mockLexerCallback.endOfTrack, 1, t)
+
+	mockLexerCallback = new(CountingLexerCallback)
+	lexer = NewMidiLexer(NewMockReadSeeker(&data), mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	err = lexer.Lex()
+
+	if !errors.Is(err, BadSizeChunk) {
+		t.Fatal("Expected BadSizeChunk got ", err)
+	}
+}
+
+// In StrictMode data bytes with the high bit set should be an error.
+func TestStrictModeBadDataByte(t *testing.T) {
+	mockLexerCallback = new(CountingLexerCallback)
+	mockReadSeeker = NewMockReadSeeker(&[]byte{0x40, 0x95, 0x04, 0x83})
+	lexer = NewMidiLexer(mockReadSeeker, mockLexerCallback)
+	lexer.SetMode(StrictMode)
+
+	lexer.state = ExpectTrackEvent
+
+	finished, err = lexer.next()
+
+	assertError(err, BadDataByte, t)
+	assertIntsEqual(mockLexerCallback.noteOn, 0, t)
 }
@@ -0,0 +1,100 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Merging tracks.
+ * Events from all the tracks of a file in the order they're played, rather than one track after another.
+ */
+
+package midi
+
+import (
+	"container/heap"
+	"iter"
+)
+
+// MergedEvents returns an iterator over the events of all the tracks in time order.
+// Events at the same time are in order of track, then the order they are in the track,
+// so the order is always the same for the same tracks.
+// Each track's events must be in time order, as they are in a File that has been read.
+func MergedEvents(tracks []Track) iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		var cursors = make(trackCursors, 0, len(tracks))
+
+		for i, track := range tracks {
+			if len(track.Events) > 0 {
+				cursors = append(cursors, trackCursor{events: track.Events, track: i})
+			}
+		}
+
+		heap.Init(&cursors)
+
+		for len(cursors) > 0 {
+			var cursor = &cursors[0]
+
+			if !yield(cursor.events[cursor.position]) {
+				return
+			}
+
+			cursor.position++
+
+			if cursor.position == len(cursor.events) {
+				heap.Pop(&cursors)
+			} else {
+				heap.Fix(&cursors, 0)
+			}
+		}
+	}
+}
+
+// MergedEvents returns an iterator over the events of all the tracks of the file in time order. See MergedEvents.
+func (file *File) MergedEvents() iter.Seq[Event] {
+	return MergedEvents(file.Tracks)
+}
+
+// trackCursor is the position of the next event to merge from a track.
+type trackCursor struct {
+	events   []Event
+	track    int
+	position int
+}
+
+// trackCursors is a heap of trackCursors, with the one with the next event first.
+type trackCursors []trackCursor
+
+func (cursors trackCursors) Len() int {
+	return len(cursors)
+}
+
+func (cursors trackCursors) Less(i int, j int) bool {
+	var iTicks = cursors[i].events[cursors[i].position].context().AbsoluteTicks
+	var jTicks = cursors[j].events[cursors[j].position].context().AbsoluteTicks
+
+	if iTicks != jTicks {
+		return iTicks < jTicks
+	}
+
+	return cursors[i].track < cursors[j].track
+}
+
+func (cursors trackCursors) Swap(i int, j int) {
+	cursors[i], cursors[j] = cursors[j], cursors[i]
+}
+
+func (cursors *trackCursors) Push(cursor any) {
+	*cursors = append(*cursors, cursor.(trackCursor))
+}
+
+func (cursors *trackCursors) Pop() any {
+	var old = *cursors
+	var cursor = old[len(old)-1]
+	*cursors = old[:len(old)-1]
+
+	return cursor
+}
@@ -0,0 +1,90 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for merging tracks.
+ */
+
+package midi
+
+import (
+	"testing"
+)
+
+// Events should come in time order, then track order, then the order in the track.
+func TestMergedEvents(t *testing.T) {
+	var tracks = []Track{
+		{Events: []Event{&Tempo{EventContext: at(0)}, &Tempo{EventContext: at(100)}, &EndOfTrack{EventContext: at(100)}}},
+		{},
+		{Events: []Event{&NoteOn{EventContext: at(0), Pitch: 1}, &NoteOn{EventContext: at(50), Pitch: 2}, &NoteOn{EventContext: at(100), Pitch: 3}}},
+		{Events: []Event{&NoteOn{EventContext: at(0), Pitch: 4}, &NoteOn{EventContext: at(0), Pitch: 5}, &NoteOn{EventContext: at(75), Pitch: 6}}},
+	}
+
+	var expected = []Event{
+		tracks[0].Events[0],
+		tracks[2].Events[0],
+		tracks[3].Events[0],
+		tracks[3].Events[1],
+		tracks[2].Events[1],
+		tracks[3].Events[2],
+		tracks[0].Events[1],
+		tracks[0].Events[2],
+		tracks[2].Events[2],
+	}
+
+	var i = 0
+
+	for event := range MergedEvents(tracks) {
+		if event != expected[i] {
+			t.Fatal("Event ", i, " expected ", expected[i], " got ", event)
+		}
+
+		i++
+	}
+
+	assertIntsEqual(i, len(expected), t)
+
+	// Stopping early.
+	i = 0
+
+	for range MergedEvents(tracks) {
+		i++
+
+		if i == 2 {
+			break
+		}
+	}
+
+	assertIntsEqual(i, 2, t)
+}
+
+// A file's tracks should be merged.
+func TestFileMergedEvents(t *testing.T) {
+	file, err := ReadFileFromBytes(testMidiFile())
+	assertNoError(err, t)
+
+	var ticks []uint64
+	var tracks []int
+
+	for event := range file.MergedEvents() {
+		ticks = append(ticks, event.Context().AbsoluteTicks)
+		tracks = append(tracks, event.Context().Track)
+	}
+
+	assertIntsEqual(len(ticks), 10, t)
+
+	// Everything at time 0, conductor track first, then the notes at 0x60 ending with the second track's EndOfTrack.
+	var expectedTracks = []int{0, 0, 0, 1, 1, 1, 1, 1, 1, 1}
+	var expectedTicks = []uint64{0, 0, 0, 0, 0, 0, 0, 0x60, 0x60, 0x60}
+
+	for i := range ticks {
+		assertIntsEqual(tracks[i], expectedTracks[i], t)
+		assertIntsEqual(int(ticks[i]), int(expectedTicks[i]), t)
+	}
+}
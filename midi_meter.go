@@ -0,0 +1,192 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * The meter map.
+ * Converts between ticks and bars and beats, taking account of time signature changes.
+ */
+
+package midi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MusicalPosition is a position in bars and beats.
+// Bars and beats are counted from 1, as they are in music. Ticks are counted from 0 within the beat.
+type MusicalPosition struct {
+	Bar   int
+	Beat  int
+	Ticks uint64
+}
+
+// String formats the position as bar:beat:tick.
+func (position MusicalPosition) String() string {
+	return fmt.Sprintf("%d:%d:%d", position.Bar, position.Beat, position.Ticks)
+}
+
+// MeterMap converts between absolute ticks and bars and beats.
+// Until the first TimeSignature the time signature is 4/4.
+// A TimeSignature part way through a bar starts a new bar, so the bar before is short.
+type MeterMap struct {
+	ticksPerQuarterNote uint16
+
+	// In order of ticks. The first is always at 0.
+	changes []meterChange
+}
+
+// A time signature change in the MeterMap.
+type meterChange struct {
+	ticks uint64
+
+	// The bar that the change starts, counted from 0.
+	bar int
+
+	numerator uint8
+
+	// As a power of 2, as in the file.
+	denominator uint8
+
+	beatTicks uint64
+}
+
+// NewMeterMap creates a MeterMap from the TimeSignature events in all the tracks of a File.
+// The File must be in MetricalTimeFormat, and not format 2, as each track has its own time. See FlattenSequence.
+func NewMeterMap(file *File) (*MeterMap, error) {
+	if file.Format == SequentialTracks {
+		return nil, WrongFormat
+	}
+
+	var timeSignatures []*TimeSignature
+
+	for event := range file.MergedEvents() {
+		if timeSignature, ok := event.(*TimeSignature); ok {
+			timeSignatures = append(timeSignatures, timeSignature)
+		}
+	}
+
+	return NewMeterMapFromEvents(file.HeaderData, timeSignatures)
+}
+
+// NewMeterMapFromEvents creates a MeterMap from TimeSignature events, in time order.
+// Only the last of several TimeSignature events at the same time counts.
+func NewMeterMapFromEvents(header HeaderData, timeSignatures []*TimeSignature) (*MeterMap, error) {
+	if header.TimeFormat != MetricalTimeFormat || header.TicksPerQuarterNote == 0 {
+		return nil, NotMetricalTimeFormat
+	}
+
+	var meterMap = &MeterMap{ticksPerQuarterNote: header.TicksPerQuarterNote}
+
+	var change, err = meterMap.newChange(0, 0, 4, 2)
+
+	if err != nil {
+		return nil, err
+	}
+
+	meterMap.changes = []meterChange{change}
+
+	for _, timeSignature := range timeSignatures {
+		var last = meterMap.changes[len(meterMap.changes)-1]
+
+		if timeSignature.AbsoluteTicks < last.ticks {
+			return nil, EventsOutOfOrder
+		}
+
+		// A partly complete bar still counts as a bar.
+		var barTicks = last.barTicks()
+		var bar = last.bar + int((timeSignature.AbsoluteTicks-last.ticks+barTicks-1)/barTicks)
+
+		change, err = meterMap.newChange(timeSignature.AbsoluteTicks, bar, timeSignature.Numerator, timeSignature.Denominator)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if timeSignature.AbsoluteTicks == last.ticks {
+			meterMap.changes[len(meterMap.changes)-1] = change
+		} else {
+			meterMap.changes = append(meterMap.changes, change)
+		}
+	}
+
+	return meterMap, nil
+}
+
+// newChange makes a meterChange, checking that the beats are a whole number of ticks.
+func (meterMap *MeterMap) newChange(ticks uint64, bar int, numerator uint8, denominator uint8) (meterChange, error) {
+	var wholeNoteTicks = uint64(meterMap.ticksPerQuarterNote) * 4
+
+	if numerator == 0 || denominator > 63 || wholeNoteTicks%(1<<denominator) != 0 {
+		return meterChange{}, InvalidTimeSignature
+	}
+
+	return meterChange{ticks: ticks, bar: bar, numerator: numerator, denominator: denominator, beatTicks: wholeNoteTicks >> denominator}, nil
+}
+
+// barTicks returns the length of a bar.
+func (change meterChange) barTicks() uint64 {
+	return uint64(change.numerator) * change.beatTicks
+}
+
+// Position returns the bar, beat and tick of an absolute tick.
+func (meterMap *MeterMap) Position(ticks uint64) MusicalPosition {
+	var i = sort.Search(len(meterMap.changes), func(i int) bool {
+		return meterMap.changes[i].ticks > ticks
+	}) - 1
+
+	var change = meterMap.changes[i]
+	var elapsed = ticks - change.ticks
+	var inBar = elapsed % change.barTicks()
+
+	return MusicalPosition{
+		Bar:   change.bar + int(elapsed/change.barTicks()) + 1,
+		Beat:  int(inBar/change.beatTicks) + 1,
+		Ticks: inBar % change.beatTicks,
+	}
+}
+
+// Ticks returns the absolute tick of a bar, beat and tick.
+// The beat must be in the bar and the tick in the beat, according to the time signature of the bar.
+func (meterMap *MeterMap) Ticks(position MusicalPosition) (uint64, error) {
+	if position.Bar < 1 || position.Beat < 1 {
+		return 0, InvalidPosition
+	}
+
+	var bar = position.Bar - 1
+
+	var i = sort.Search(len(meterMap.changes), func(i int) bool {
+		return meterMap.changes[i].bar > bar
+	}) - 1
+
+	var change = meterMap.changes[i]
+
+	if position.Beat > int(change.numerator) || position.Ticks >= change.beatTicks {
+		return 0, InvalidPosition
+	}
+
+	var ticks = change.ticks + uint64(bar-change.bar)*change.barTicks() + uint64(position.Beat-1)*change.beatTicks + position.Ticks
+
+	// A short bar before a time signature change doesn't have all its beats.
+	if i+1 < len(meterMap.changes) && ticks >= meterMap.changes[i+1].ticks {
+		return 0, InvalidPosition
+	}
+
+	return ticks, nil
+}
+
+// TimeSignature returns the numerator and denominator of the time signature at an absolute tick.
+// The denominator is as it's written in music, e.g. 8 for 6/8.
+func (meterMap *MeterMap) TimeSignature(ticks uint64) (numerator int, denominator int) {
+	var i = sort.Search(len(meterMap.changes), func(i int) bool {
+		return meterMap.changes[i].ticks > ticks
+	}) - 1
+
+	return int(meterMap.changes[i].numerator), 1 << meterMap.changes[i].denominator
+}
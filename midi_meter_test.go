@@ -0,0 +1,130 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the meter map.
+ */
+
+package midi
+
+import (
+	"testing"
+)
+
+// assertPosition asserts that the meter map converts between ticks and a position, both ways.
+func assertPosition(meterMap *MeterMap, ticks uint64, expected MusicalPosition, t *testing.T) {
+	var position = meterMap.Position(ticks)
+
+	if position != expected {
+		t.Fatal("Ticks ", ticks, " expected ", expected, " got ", position)
+	}
+
+	result, err := meterMap.Ticks(position)
+	assertNoError(err, t)
+	assertIntsEqual(int(result), int(ticks), t)
+}
+
+// Without any time signatures, the time signature is 4/4.
+func TestMeterMapDefault(t *testing.T) {
+	meterMap, err := NewMeterMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, nil)
+	assertNoError(err, t)
+
+	assertPosition(meterMap, 0, MusicalPosition{1, 1, 0}, t)
+	assertPosition(meterMap, 95, MusicalPosition{1, 1, 95}, t)
+	assertPosition(meterMap, 96, MusicalPosition{1, 2, 0}, t)
+	assertPosition(meterMap, 96*4+10, MusicalPosition{2, 1, 10}, t)
+
+	numerator, denominator := meterMap.TimeSignature(1000)
+	assertIntsEqual(numerator, 4, t)
+	assertIntsEqual(denominator, 4, t)
+
+	assertStringsEqual(meterMap.Position(96*4+10).String(), "2:1:10", t)
+}
+
+// Positions should take account of each time signature change.
+func TestMeterMapChanges(t *testing.T) {
+	var timeSignatures = []*TimeSignature{
+		// 3/4 from the start, replacing 4/4.
+		{EventContext: at(0), Numerator: 3, Denominator: 2},
+
+		// 6/8 from bar 3.
+		{EventContext: at(96 * 3 * 2), Numerator: 6, Denominator: 3},
+
+		// 2/2 half way through bar 4, so bar 4 is short.
+		{EventContext: at(96*9 + 48*3), Numerator: 2, Denominator: 1},
+	}
+
+	meterMap, err := NewMeterMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, timeSignatures)
+	assertNoError(err, t)
+
+	assertPosition(meterMap, 0, MusicalPosition{1, 1, 0}, t)
+	assertPosition(meterMap, 96*3, MusicalPosition{2, 1, 0}, t)
+	assertPosition(meterMap, 96*5+1, MusicalPosition{2, 3, 1}, t)
+
+	// Beats are quavers in 6/8.
+	assertPosition(meterMap, 96*6, MusicalPosition{3, 1, 0}, t)
+	assertPosition(meterMap, 96*6+48*5+47, MusicalPosition{3, 6, 47}, t)
+	assertPosition(meterMap, 96*9, MusicalPosition{4, 1, 0}, t)
+	assertPosition(meterMap, 96*9+48*2+1, MusicalPosition{4, 3, 1}, t)
+
+	// Minims in 2/2, from bar 5.
+	assertPosition(meterMap, 96*9+48*3, MusicalPosition{5, 1, 0}, t)
+	assertPosition(meterMap, 96*9+48*3+192, MusicalPosition{5, 2, 0}, t)
+	assertPosition(meterMap, 96*9+48*3+192*2+5, MusicalPosition{6, 1, 5}, t)
+
+	numerator, denominator := meterMap.TimeSignature(96 * 7)
+	assertIntsEqual(numerator, 6, t)
+	assertIntsEqual(denominator, 8, t)
+
+	// The short bar doesn't have its later beats.
+	_, err = meterMap.Ticks(MusicalPosition{4, 4, 0})
+	assertError(err, InvalidPosition, t)
+
+	// Beats and ticks have to be in range.
+	_, err = meterMap.Ticks(MusicalPosition{1, 4, 0})
+	assertError(err, InvalidPosition, t)
+	_, err = meterMap.Ticks(MusicalPosition{1, 1, 96})
+	assertError(err, InvalidPosition, t)
+	_, err = meterMap.Ticks(MusicalPosition{0, 1, 0})
+	assertError(err, InvalidPosition, t)
+}
+
+// The meter map of a file should use time signatures from any track.
+func TestNewMeterMap(t *testing.T) {
+	file, err := ReadFileFromBytes(testMidiFile())
+	assertNoError(err, t)
+
+	var timeSignature = file.Tracks[0].Events[1].(*TimeSignature)
+	assertIntsEqual(timeSignature.DenominatorValue(), 4, t)
+	timeSignature.Numerator = 3
+
+	meterMap, err := NewMeterMap(file)
+	assertNoError(err, t)
+	assertPosition(meterMap, 96*3, MusicalPosition{2, 1, 0}, t)
+
+	file.Format = SequentialTracks
+	_, err = NewMeterMap(file)
+	assertError(err, WrongFormat, t)
+}
+
+// Time signatures that can't be counted in ticks should be errors.
+func TestMeterMapErrors(t *testing.T) {
+	_, err := NewMeterMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, []*TimeSignature{{Numerator: 0, Denominator: 2}})
+	assertError(err, InvalidTimeSignature, t)
+
+	// 96 * 4 / 256 isn't a whole number.
+	_, err = NewMeterMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, []*TimeSignature{{Numerator: 4, Denominator: 8}})
+	assertError(err, InvalidTimeSignature, t)
+
+	_, err = NewMeterMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, []*TimeSignature{{EventContext: at(10), Numerator: 4, Denominator: 2}, {EventContext: at(5), Numerator: 4, Denominator: 2}})
+	assertError(err, EventsOutOfOrder, t)
+
+	_, err = NewMeterMapFromEvents(HeaderData{TimeFormat: TimeCodeTimeFormat, TicksPerQuarterNote: 96}, nil)
+	assertError(err, NotMetricalTimeFormat, t)
+}
@@ -0,0 +1,157 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Notes.
+ * Pairs NoteOn events with NoteOff events to make notes with durations.
+ */
+
+package midi
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// How a NoteOff is matched when several notes of the same pitch on the same channel are sounding.
+const (
+	// The NoteOff ends the note that started first.
+	FIFONoteMatching = iota
+
+	// The NoteOff ends the note that started last.
+	LIFONoteMatching = iota
+)
+
+// Note is a note with a duration, made from a NoteOn and the NoteOff that ends it.
+type Note struct {
+	Channel  uint8
+	Pitch    uint8
+	Velocity uint8
+
+	// The velocity of the NoteOff. Zero if the note was ended by a NoteOn with velocity 0, or by the end of the track.
+	ReleaseVelocity uint8
+
+	StartTick     uint64
+	DurationTicks uint64
+
+	// The index of the track.
+	Track int
+}
+
+// EndTick returns the absolute tick at which the note ends.
+func (note Note) EndTick() uint64 {
+	return note.StartTick + note.DurationTicks
+}
+
+// String describes the note.
+func (note Note) String() string {
+	return fmt.Sprintf("Note channel %d, pitch %d, velocity %d, release velocity %d at track %d, tick %d for %d", note.Channel, note.Pitch, note.Velocity, note.ReleaseVelocity, note.Track, note.StartTick, note.DurationTicks)
+}
+
+// ExtractNotes pairs the NoteOns and NoteOffs in each track of a File. See PairNotes.
+// The notes are in order of start time, then track.
+func ExtractNotes(file *File, matching int) (notes []Note, orphans []Event) {
+	for i, track := range file.Tracks {
+		trackNotes, trackOrphans := PairNotes(track, i, matching)
+
+		notes = append(notes, trackNotes...)
+		orphans = append(orphans, trackOrphans...)
+	}
+
+	slices.SortStableFunc(notes, func(a Note, b Note) int {
+		if a.StartTick != b.StartTick {
+			return cmp.Compare(a.StartTick, b.StartTick)
+		}
+
+		return cmp.Compare(a.Track, b.Track)
+	})
+
+	return notes, orphans
+}
+
+// PairNotes pairs the NoteOns and NoteOffs in a track, the index of which is given, to make Notes.
+// A NoteOn with velocity 0 is a NoteOff. Notes still sounding at the end of the track end at the EndOfTrack,
+// or the last event if there isn't one. NoteOffs without a sounding note to end are returned as orphans.
+// The notes are in the order they start.
+func PairNotes(track Track, trackIndex int, matching int) (notes []Note, orphans []Event) {
+	notes, _, orphans = pairNoteEvents(track, trackIndex, matching)
+
+	return notes, orphans
+}
+
+// noteEvents are the events that start and end a Note. The end is nil if the note ends at the end of the track.
+type noteEvents struct {
+	start *NoteOn
+	end   Event
+}
+
+// pairNoteEvents does PairNotes, also returning the events of each note.
+func pairNoteEvents(track Track, trackIndex int, matching int) (notes []Note, events []noteEvents, orphans []Event) {
+	// The indexes of the notes sounding for each channel and pitch, in the order they started.
+	var sounding = make(map[uint16][]int)
+	var end uint64 = 0
+
+	for _, event := range track.Events {
+		var ticks = event.context().AbsoluteTicks
+		end = max(end, ticks)
+
+		var channel, pitch, releaseVelocity uint8
+
+		switch event := event.(type) {
+		case *NoteOn:
+			if event.Velocity > 0 {
+				var key = uint16(event.Channel)<<7 | uint16(event.Pitch)
+				sounding[key] = append(sounding[key], len(notes))
+
+				notes = append(notes, Note{Channel: event.Channel, Pitch: event.Pitch, Velocity: event.Velocity, StartTick: ticks, Track: trackIndex})
+				events = append(events, noteEvents{start: event})
+				continue
+			}
+
+			channel, pitch = event.Channel, event.Pitch
+
+		case *NoteOff:
+			channel, pitch, releaseVelocity = event.Channel, event.Pitch, event.Velocity
+
+		default:
+			continue
+		}
+
+		var key = uint16(channel)<<7 | uint16(pitch)
+		var queue = sounding[key]
+
+		if len(queue) == 0 {
+			orphans = append(orphans, event)
+			continue
+		}
+
+		var index int
+
+		if matching == LIFONoteMatching {
+			index = queue[len(queue)-1]
+			sounding[key] = queue[:len(queue)-1]
+		} else {
+			index = queue[0]
+			sounding[key] = queue[1:]
+		}
+
+		notes[index].DurationTicks = ticks - notes[index].StartTick
+		notes[index].ReleaseVelocity = releaseVelocity
+		events[index].end = event
+	}
+
+	for _, queue := range sounding {
+		for _, index := range queue {
+			notes[index].DurationTicks = end - notes[index].StartTick
+		}
+	}
+
+	return notes, events, orphans
+}
@@ -0,0 +1,160 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for pairing notes.
+ */
+
+package midi
+
+import (
+	"testing"
+)
+
+// assertNote asserts the pitch, times and velocities of a note.
+func assertNote(note Note, pitch uint8, start uint64, duration uint64, velocity uint8, releaseVelocity uint8, t *testing.T) {
+	assertUint8sEqual(note.Pitch, pitch, t)
+	assertIntsEqual(int(note.StartTick), int(start), t)
+	assertIntsEqual(int(note.DurationTicks), int(duration), t)
+	assertUint8sEqual(note.Velocity, velocity, t)
+	assertUint8sEqual(note.ReleaseVelocity, releaseVelocity, t)
+}
+
+// NoteOns should be paired with NoteOffs, and NoteOns with velocity 0.
+func TestPairNotes(t *testing.T) {
+	var track = Track{Events: []Event{
+		&NoteOn{EventContext: at(0), Channel: 1, Pitch: 60, Velocity: 100},
+		&NoteOn{EventContext: at(10), Channel: 1, Pitch: 64, Velocity: 90},
+		&NoteOff{EventContext: at(20), Channel: 1, Pitch: 60, Velocity: 40},
+		&NoteOn{EventContext: at(30), Channel: 1, Pitch: 64, Velocity: 0},
+		&EndOfTrack{EventContext: at(40)},
+	}}
+
+	var notes, orphans = PairNotes(track, 3, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertIntsEqual(len(orphans), 0, t)
+
+	assertNote(notes[0], 60, 0, 20, 100, 40, t)
+	assertNote(notes[1], 64, 10, 20, 90, 0, t)
+
+	assertUint8sEqual(notes[0].Channel, 1, t)
+	assertIntsEqual(notes[0].Track, 3, t)
+	assertIntsEqual(int(notes[0].EndTick()), 20, t)
+}
+
+// The same pitch on different channels makes different notes.
+func TestPairNotesChannels(t *testing.T) {
+	var track = Track{Events: []Event{
+		&NoteOn{EventContext: at(0), Channel: 1, Pitch: 60, Velocity: 100},
+		&NoteOn{EventContext: at(10), Channel: 2, Pitch: 60, Velocity: 100},
+		&NoteOff{EventContext: at(20), Channel: 2, Pitch: 60},
+		&NoteOff{EventContext: at(30), Channel: 1, Pitch: 60},
+	}}
+
+	var notes, _ = PairNotes(track, 0, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 30, 100, 0, t)
+	assertNote(notes[1], 60, 10, 10, 100, 0, t)
+}
+
+// Overlapping notes of the same pitch should be matched according to the policy.
+func TestPairNotesOverlapping(t *testing.T) {
+	var track = Track{Events: []Event{
+		&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 1},
+		&NoteOn{EventContext: at(10), Pitch: 60, Velocity: 2},
+		&NoteOff{EventContext: at(20), Pitch: 60},
+		&NoteOff{EventContext: at(40), Pitch: 60},
+	}}
+
+	var notes, _ = PairNotes(track, 0, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 20, 1, 0, t)
+	assertNote(notes[1], 60, 10, 30, 2, 0, t)
+
+	notes, _ = PairNotes(track, 0, LIFONoteMatching)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 40, 1, 0, t)
+	assertNote(notes[1], 60, 10, 10, 2, 0, t)
+}
+
+// Notes still sounding should end at the End Of Track, or the last event without one.
+func TestPairNotesUnfinished(t *testing.T) {
+	var track = Track{Events: []Event{
+		&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 100},
+		&NoteOn{EventContext: at(10), Pitch: 62, Velocity: 100},
+		&EndOfTrack{EventContext: at(50)},
+	}}
+
+	var notes, _ = PairNotes(track, 0, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 50, 100, 0, t)
+	assertNote(notes[1], 62, 10, 40, 100, 0, t)
+
+	track.Events[2] = &ControlChange{EventContext: at(30)}
+
+	notes, _ = PairNotes(track, 0, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 30, 100, 0, t)
+	assertNote(notes[1], 62, 10, 20, 100, 0, t)
+}
+
+// NoteOffs without a sounding note should be returned.
+func TestPairNotesOrphans(t *testing.T) {
+	var track = Track{Events: []Event{
+		&NoteOff{EventContext: at(0), Pitch: 60},
+		&NoteOn{EventContext: at(10), Pitch: 60, Velocity: 100},
+		&NoteOff{EventContext: at(20), Pitch: 60},
+		&NoteOn{EventContext: at(30), Pitch: 60, Velocity: 0},
+	}}
+
+	var notes, orphans = PairNotes(track, 0, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 1, t)
+	assertNote(notes[0], 60, 10, 10, 100, 0, t)
+
+	assertIntsEqual(len(orphans), 2, t)
+	assertTrue(orphans[0] == track.Events[0], t)
+	assertTrue(orphans[1] == track.Events[3], t)
+}
+
+// Notes from all tracks should be in order of start, then track.
+func TestExtractNotes(t *testing.T) {
+	var file = File{Tracks: []Track{
+		{Events: []Event{
+			&NoteOn{EventContext: at(10), Pitch: 1, Velocity: 100},
+			&NoteOff{EventContext: at(20), Pitch: 1},
+			&NoteOff{EventContext: at(30), Pitch: 9},
+		}},
+		{Events: []Event{
+			&NoteOn{EventContext: at(0), Pitch: 2, Velocity: 100},
+			&NoteOn{EventContext: at(10), Pitch: 3, Velocity: 100},
+			&NoteOff{EventContext: at(40), Pitch: 2},
+			&NoteOff{EventContext: at(40), Pitch: 3},
+		}},
+	}}
+
+	var notes, orphans = ExtractNotes(&file, FIFONoteMatching)
+
+	assertIntsEqual(len(notes), 3, t)
+	assertNote(notes[0], 2, 0, 40, 100, 0, t)
+	assertNote(notes[1], 1, 10, 10, 100, 0, t)
+	assertNote(notes[2], 3, 10, 30, 100, 0, t)
+
+	assertIntsEqual(notes[0].Track, 1, t)
+	assertIntsEqual(notes[1].Track, 0, t)
+
+	assertIntsEqual(len(orphans), 1, t)
+	assertTrue(orphans[0] == file.Tracks[0].Events[2], t)
+}
@@ -0,0 +1,170 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Pedals.
+ * Lengthens notes to how long they sound with the sustain and sostenuto pedals.
+ */
+
+package midi
+
+import (
+	"slices"
+	"sort"
+)
+
+// Controller numbers of ControlChange events for pedals.
+const (
+	SustainPedal   = 64
+	SostenutoPedal = 66
+)
+
+// A pedal is down when the value of its ControlChange is at least this.
+const pedalDownValue = 64
+
+// pedalInterval is a time when a pedal is down, from down up to but not including up.
+type pedalInterval struct {
+	down uint64
+	up   uint64
+}
+
+// ApplyPedals returns copies of the notes, from ExtractNotes, lengthened to how long they sound with the
+// sustain and sostenuto pedals in the File. Pedals apply to all notes on their channel, whatever the track.
+// A note released while the sustain pedal is down sounds until it's lifted. A note held when the sostenuto
+// pedal goes down sounds until it's lifted. A lengthened note stops when the same pitch is played again,
+// and a pedal still down at the end stops at the last event in the File.
+// The File must not be format 2, as each track has its own time.
+func ApplyPedals(file *File, notes []Note) ([]Note, error) {
+	if file.Format == SequentialTracks {
+		return nil, WrongFormat
+	}
+
+	var sustain, sostenuto = pedalIntervals(file)
+
+	// The start times of the notes of each channel and pitch, in order.
+	var starts = make(map[uint16][]uint64)
+
+	for _, note := range notes {
+		var key = uint16(note.Channel)<<7 | uint16(note.Pitch)
+		starts[key] = append(starts[key], note.StartTick)
+	}
+
+	for _, ticks := range starts {
+		slices.Sort(ticks)
+	}
+
+	var result = make([]Note, len(notes))
+
+	for i, note := range notes {
+		var end = note.EndTick()
+
+		// The last time the sostenuto pedal went down while the note was held.
+		var pedal = sostenuto[note.Channel]
+		var last = sort.Search(len(pedal), func(i int) bool { return pedal[i].down >= end }) - 1
+
+		if last >= 0 && pedal[last].down >= note.StartTick {
+			end = max(end, pedal[last].up)
+		}
+
+		if interval, ok := findPedalInterval(sustain[note.Channel], end); ok {
+			end = interval.up
+		}
+
+		var key = uint16(note.Channel)<<7 | uint16(note.Pitch)
+		var pitchStarts = starts[key]
+		var next = sort.Search(len(pitchStarts), func(i int) bool { return pitchStarts[i] > note.StartTick })
+
+		if next < len(pitchStarts) {
+			end = min(end, max(note.EndTick(), pitchStarts[next]))
+		}
+
+		note.DurationTicks = end - note.StartTick
+		result[i] = note
+	}
+
+	return result, nil
+}
+
+// RemovePedals returns a copy of the File without the sustain and sostenuto pedal ControlChanges,
+// e.g. after using ApplyPedals.
+func RemovePedals(file *File) *File {
+	var result = copyFile(file)
+
+	for i := range result.Tracks {
+		var track = &result.Tracks[i]
+
+		track.Events = slices.DeleteFunc(track.Events, isPedal)
+		updateContexts(track.Events, i)
+	}
+
+	return result
+}
+
+// isPedal returns whether an event is a sustain or sostenuto pedal ControlChange.
+func isPedal(event Event) bool {
+	var controlChange, ok = event.(*ControlChange)
+
+	return ok && (controlChange.Controller == SustainPedal || controlChange.Controller == SostenutoPedal)
+}
+
+// pedalIntervals returns the times the sustain and sostenuto pedals are down on each channel, in order.
+func pedalIntervals(file *File) (sustain map[uint8][]pedalInterval, sostenuto map[uint8][]pedalInterval) {
+	sustain = make(map[uint8][]pedalInterval)
+	sostenuto = make(map[uint8][]pedalInterval)
+
+	// When each pedal of each channel went down, if it's down now.
+	var down = make(map[uint16]uint64)
+	var end uint64 = 0
+
+	for event := range file.MergedEvents() {
+		end = max(end, event.context().AbsoluteTicks)
+
+		if !isPedal(event) {
+			continue
+		}
+
+		var controlChange = event.(*ControlChange)
+		var key = uint16(controlChange.Channel)<<7 | uint16(controlChange.Controller)
+		var since, isDown = down[key]
+
+		if controlChange.Value >= pedalDownValue && !isDown {
+			down[key] = controlChange.AbsoluteTicks
+		} else if controlChange.Value < pedalDownValue && isDown {
+			delete(down, key)
+			addPedalInterval(sustain, sostenuto, controlChange.Channel, controlChange.Controller, pedalInterval{since, controlChange.AbsoluteTicks})
+		}
+	}
+
+	// Pedals still down stop at the end.
+	for key, since := range down {
+		addPedalInterval(sustain, sostenuto, uint8(key>>7), uint8(key&0x7F), pedalInterval{since, end})
+	}
+
+	return sustain, sostenuto
+}
+
+// addPedalInterval adds an interval for the given channel and controller.
+func addPedalInterval(sustain map[uint8][]pedalInterval, sostenuto map[uint8][]pedalInterval, channel uint8, controller uint8, interval pedalInterval) {
+	if controller == SustainPedal {
+		sustain[channel] = append(sustain[channel], interval)
+	} else {
+		sostenuto[channel] = append(sostenuto[channel], interval)
+	}
+}
+
+// findPedalInterval returns the interval in which the pedal is down at the given time, if there is one.
+func findPedalInterval(intervals []pedalInterval, ticks uint64) (pedalInterval, bool) {
+	var i = sort.Search(len(intervals), func(i int) bool { return intervals[i].up > ticks })
+
+	if i < len(intervals) && intervals[i].down <= ticks {
+		return intervals[i], true
+	}
+
+	return pedalInterval{}, false
+}
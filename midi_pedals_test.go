@@ -0,0 +1,161 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for pedals.
+ */
+
+package midi
+
+import (
+	"testing"
+)
+
+// pedal makes a pedal ControlChange.
+func pedal(ticks uint64, channel uint8, controller uint8, value uint8) *ControlChange {
+	return &ControlChange{EventContext: at(ticks), Channel: channel, Controller: controller, Value: value}
+}
+
+// applyPedals pairs the notes of a one track file and applies the pedals.
+func applyPedals(events []Event, t *testing.T) []Note {
+	var file = File{HeaderData: HeaderData{Format: SingleMultiTrackChannel}, Tracks: []Track{{Events: events}}}
+	var notes, _ = ExtractNotes(&file, FIFONoteMatching)
+	var result, err = ApplyPedals(&file, notes)
+	assertNoError(err, t)
+
+	return result
+}
+
+// Notes released while the sustain pedal is down should sound until it's lifted.
+func TestSustainPedal(t *testing.T) {
+	var notes = applyPedals([]Event{
+		&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 100},
+		&NoteOn{EventContext: at(0), Pitch: 64, Velocity: 100},
+		&NoteOff{EventContext: at(10), Pitch: 60},
+		pedal(20, 0, SustainPedal, 127),
+		&NoteOn{EventContext: at(30), Pitch: 67, Velocity: 100},
+		&NoteOff{EventContext: at(40), Pitch: 64},
+		&NoteOff{EventContext: at(50), Pitch: 67},
+		pedal(80, 0, SustainPedal, 0),
+		&NoteOn{EventContext: at(90), Pitch: 72, Velocity: 100},
+		&NoteOff{EventContext: at(100), Pitch: 72},
+	}, t)
+
+	assertIntsEqual(len(notes), 4, t)
+
+	// Released before the pedal.
+	assertNote(notes[0], 60, 0, 10, 100, 0, t)
+
+	// Released while the pedal was down.
+	assertNote(notes[1], 64, 0, 80, 100, 0, t)
+	assertNote(notes[2], 67, 30, 50, 100, 0, t)
+
+	// After the pedal.
+	assertNote(notes[3], 72, 90, 10, 100, 0, t)
+}
+
+// Pedals should only apply to their own channel, and a pedal still down should stop at the end.
+func TestSustainPedalChannels(t *testing.T) {
+	var notes = applyPedals([]Event{
+		pedal(0, 1, SustainPedal, 64),
+		&NoteOn{EventContext: at(0), Channel: 1, Pitch: 60, Velocity: 100},
+		&NoteOn{EventContext: at(0), Channel: 2, Pitch: 60, Velocity: 100},
+		&NoteOff{EventContext: at(10), Channel: 1, Pitch: 60},
+		&NoteOff{EventContext: at(10), Channel: 2, Pitch: 60},
+		&EndOfTrack{EventContext: at(200)},
+	}, t)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 200, 100, 0, t)
+	assertNote(notes[1], 60, 0, 10, 100, 0, t)
+}
+
+// A sustained note should stop when the same pitch is played again.
+func TestSustainPedalRepeatedNote(t *testing.T) {
+	var notes = applyPedals([]Event{
+		pedal(0, 0, SustainPedal, 127),
+		&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 100},
+		&NoteOff{EventContext: at(10), Pitch: 60},
+		&NoteOn{EventContext: at(30), Pitch: 60, Velocity: 90},
+		&NoteOff{EventContext: at(40), Pitch: 60},
+		pedal(100, 0, SustainPedal, 0),
+	}, t)
+
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 30, 100, 0, t)
+	assertNote(notes[1], 60, 30, 70, 90, 0, t)
+}
+
+// Only notes held when the sostenuto pedal goes down should sound until it's lifted.
+func TestSostenutoPedal(t *testing.T) {
+	var notes = applyPedals([]Event{
+		&NoteOn{EventContext: at(0), Pitch: 48, Velocity: 100},
+		&NoteOn{EventContext: at(0), Pitch: 50, Velocity: 100},
+		&NoteOff{EventContext: at(5), Pitch: 50},
+		pedal(10, 0, SostenutoPedal, 127),
+		&NoteOff{EventContext: at(20), Pitch: 48},
+		&NoteOn{EventContext: at(20), Pitch: 60, Velocity: 100},
+		&NoteOff{EventContext: at(30), Pitch: 60},
+		pedal(100, 0, SostenutoPedal, 0),
+	}, t)
+
+	assertIntsEqual(len(notes), 3, t)
+
+	// Held when the pedal went down.
+	assertNote(notes[0], 48, 0, 100, 100, 0, t)
+
+	// Released before the pedal.
+	assertNote(notes[1], 50, 0, 5, 100, 0, t)
+
+	// Played after the pedal.
+	assertNote(notes[2], 60, 20, 10, 100, 0, t)
+}
+
+// A note held by the sostenuto pedal until after the sustain pedal goes down should be held by that too.
+func TestSostenutoAndSustainPedals(t *testing.T) {
+	var notes = applyPedals([]Event{
+		&NoteOn{EventContext: at(0), Pitch: 48, Velocity: 100},
+		pedal(10, 0, SostenutoPedal, 127),
+		&NoteOff{EventContext: at(20), Pitch: 48},
+		pedal(50, 0, SustainPedal, 127),
+		pedal(60, 0, SostenutoPedal, 0),
+		pedal(90, 0, SustainPedal, 0),
+	}, t)
+
+	assertIntsEqual(len(notes), 1, t)
+	assertNote(notes[0], 48, 0, 90, 100, 0, t)
+}
+
+// Format 2 files can't have pedals applied.
+func TestApplyPedalsWrongFormat(t *testing.T) {
+	var file = File{HeaderData: HeaderData{Format: SequentialTracks}}
+	var _, err = ApplyPedals(&file, nil)
+	assertError(err, WrongFormat, t)
+}
+
+// RemovePedals should remove only the pedals, and update the delta times.
+func TestRemovePedals(t *testing.T) {
+	var file = File{Tracks: []Track{{Events: []Event{
+		&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 100},
+		pedal(10, 0, SustainPedal, 127),
+		pedal(15, 0, 7, 100),
+		pedal(20, 0, SostenutoPedal, 127),
+		&NoteOff{EventContext: at(30), Pitch: 60},
+	}}}}
+
+	var result = RemovePedals(&file)
+
+	assertIntsEqual(len(result.Tracks[0].Events), 3, t)
+	assertUint8sEqual(result.Tracks[0].Events[1].(*ControlChange).Controller, 7, t)
+	assertUint32Equal(result.Tracks[0].Events[1].Context().DeltaTicks, 15, t)
+	assertUint32Equal(result.Tracks[0].Events[2].Context().DeltaTicks, 15, t)
+
+	// The original is unchanged.
+	assertIntsEqual(len(file.Tracks[0].Events), 5, t)
+}
@@ -0,0 +1,217 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Quantize.
+ * Moves the times of notes towards a grid.
+ */
+
+package midi
+
+import (
+	"cmp"
+	"slices"
+)
+
+// QuantizeSettings says how a Quantizer moves notes.
+type QuantizeSettings struct {
+	// The note value of the grid, as a division of a whole note, e.g. 4 for crotchets or 16 for semiquavers.
+	Grid int
+
+	// Whether the grid is of triplets, three in the time of two of the note value.
+	Triplet bool
+
+	// How far to move a time towards the nearest grid line, as a percentage, from 0 to 100.
+	Strength int
+
+	// How far every second grid line is delayed, as a percentage of the grid spacing, from 0 to 99.
+	// 0 is straight, about 33 is triplet swing.
+	Swing int
+
+	// Only move times this close to a grid line, as a percentage of the grid spacing, from 0 to 100.
+	// 0 moves all times.
+	Window int
+
+	// Whether to move the ends of notes too. Otherwise notes keep their durations.
+	QuantizeEnds bool
+}
+
+// Quantizer moves the times of notes towards a grid that starts at tick 0.
+type Quantizer struct {
+	settings QuantizeSettings
+
+	// Ticks between grid lines.
+	spacing uint64
+}
+
+// NewQuantizer creates a Quantizer for a File with the given header, which must be in MetricalTimeFormat with some ticks per quarter note.
+// Returns InvalidQuantizeSettings if the grid isn't a whole number of ticks or the percentages are out of range.
+func NewQuantizer(header HeaderData, settings QuantizeSettings) (*Quantizer, error) {
+	if header.TimeFormat != MetricalTimeFormat || header.TicksPerQuarterNote == 0 {
+		return nil, NotMetricalTimeFormat
+	}
+
+	var wholeNote = uint64(header.TicksPerQuarterNote) * 4
+	var divisions = uint64(max(settings.Grid, 0))
+
+	if settings.Triplet {
+		divisions = divisions * 3 / 2
+	}
+
+	if settings.Grid <= 0 || settings.Triplet && settings.Grid%2 != 0 || wholeNote%divisions != 0 ||
+		settings.Strength < 0 || settings.Strength > 100 ||
+		settings.Swing < 0 || settings.Swing > 99 ||
+		settings.Window < 0 || settings.Window > 100 {
+		return nil, InvalidQuantizeSettings
+	}
+
+	return &Quantizer{settings: settings, spacing: wholeNote / divisions}, nil
+}
+
+// Ticks returns the quantized time.
+func (quantizer *Quantizer) Ticks(ticks uint64) uint64 {
+	var spacing = int64(quantizer.spacing)
+	var time = int64(ticks)
+
+	// Grid lines come in pairs, the second one delayed by the swing.
+	var pair = time / (spacing * 2) * spacing * 2
+	var swung = pair + spacing + spacing*int64(quantizer.settings.Swing)/100
+
+	var nearest = pair
+
+	for _, line := range []int64{swung, pair + spacing*2} {
+		if abs(line-time) < abs(nearest-time) {
+			nearest = line
+		}
+	}
+
+	var distance = nearest - time
+
+	if quantizer.settings.Window > 0 && abs(distance)*100 > spacing*int64(quantizer.settings.Window) {
+		return ticks
+	}
+
+	return uint64(time + roundedDivide(distance*int64(quantizer.settings.Strength), 100))
+}
+
+// QuantizeNotes returns copies of the notes with their times quantized.
+// A note whose quantized end isn't after its start is made one grid spacing long.
+func (quantizer *Quantizer) QuantizeNotes(notes []Note) []Note {
+	var result = make([]Note, len(notes))
+
+	for i, note := range notes {
+		result[i] = quantizer.quantizeNote(note)
+	}
+
+	return result
+}
+
+// QuantizeFile returns a copy of the File with the NoteOns and NoteOffs in each track quantized, paired with
+// PairNotes using the given matching. NoteOffs at the same time as NoteOns are put first, so no note is ended by
+// the NoteOff of another, and notes are at least one tick long. The EndOfTrack is moved after any note moved past it.
+// Format 2 files are quantized as each track has its own time.
+func (quantizer *Quantizer) QuantizeFile(file *File, matching int) *File {
+	var result = copyFile(file)
+
+	for i := range result.Tracks {
+		var track = &result.Tracks[i]
+		var notes, events, _ = pairNoteEvents(*track, i, matching)
+
+		for j, note := range notes {
+			note = quantizer.quantizeNote(note)
+			events[j].start.AbsoluteTicks = note.StartTick
+
+			if events[j].end != nil {
+				events[j].end.context().AbsoluteTicks = note.StartTick + max(note.DurationTicks, 1)
+			}
+		}
+
+		var endOfTrack *EndOfTrack
+
+		track.Events = slices.DeleteFunc(track.Events, func(event Event) bool {
+			if event, ok := event.(*EndOfTrack); ok {
+				endOfTrack = event
+				return true
+			}
+
+			return false
+		})
+
+		slices.SortStableFunc(track.Events, func(a Event, b Event) int {
+			if a.context().AbsoluteTicks != b.context().AbsoluteTicks {
+				return cmp.Compare(a.context().AbsoluteTicks, b.context().AbsoluteTicks)
+			}
+
+			return cmp.Compare(noteOrder(a), noteOrder(b))
+		})
+
+		if endOfTrack != nil {
+			if len(track.Events) > 0 {
+				endOfTrack.AbsoluteTicks = max(endOfTrack.AbsoluteTicks, track.Events[len(track.Events)-1].context().AbsoluteTicks)
+			}
+
+			track.Events = append(track.Events, endOfTrack)
+		}
+
+		updateContexts(track.Events, i)
+	}
+
+	return result
+}
+
+// quantizeNote returns the note with its times quantized.
+func (quantizer *Quantizer) quantizeNote(note Note) Note {
+	var start = quantizer.Ticks(note.StartTick)
+
+	if quantizer.settings.QuantizeEnds {
+		var end = quantizer.Ticks(note.EndTick())
+
+		if end <= start {
+			end = start + quantizer.spacing
+		}
+
+		note.DurationTicks = end - start
+	}
+
+	note.StartTick = start
+
+	return note
+}
+
+// noteOrder orders events at the same time, NoteOffs first.
+func noteOrder(event Event) int {
+	switch event := event.(type) {
+	case *NoteOff:
+		return 0
+	case *NoteOn:
+		if event.Velocity == 0 {
+			return 0
+		}
+	}
+
+	return 1
+}
+
+// abs returns the absolute value.
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+// roundedDivide divides, rounding to the nearest, with halves away from zero.
+func roundedDivide(value int64, divisor int64) int64 {
+	if value < 0 {
+		return -((-value + divisor/2) / divisor)
+	}
+
+	return (value + divisor/2) / divisor
+}
@@ -0,0 +1,174 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for quantizing.
+ */
+
+package midi
+
+import (
+	"testing"
+)
+
+// newTestQuantizer makes a Quantizer for 96 ticks per quarter note.
+func newTestQuantizer(settings QuantizeSettings, t *testing.T) *Quantizer {
+	var quantizer, err = NewQuantizer(HeaderData{TimeFormat: MetricalTimeFormat, TicksPerQuarterNote: 96}, settings)
+	assertNoError(err, t)
+
+	return quantizer
+}
+
+// assertQuantized asserts the quantized times of some times.
+func assertQuantized(quantizer *Quantizer, times []uint64, expected []uint64, t *testing.T) {
+	for i, ticks := range times {
+		assertIntsEqual(int(quantizer.Ticks(ticks)), int(expected[i]), t)
+	}
+}
+
+// Times should move to the nearest grid line, earlier on a tie.
+func TestQuantizeTicks(t *testing.T) {
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 16, Strength: 100}, t)
+
+	assertQuantized(quantizer, []uint64{0, 11, 12, 13, 24, 35, 100}, []uint64{0, 0, 0, 24, 24, 24, 96}, t)
+}
+
+// Triplet grids have three lines in the time of two.
+func TestQuantizeTriplets(t *testing.T) {
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 8, Triplet: true, Strength: 100}, t)
+
+	assertQuantized(quantizer, []uint64{20, 40, 60, 90}, []uint64{32, 32, 64, 96}, t)
+}
+
+// Strength should move times part of the way.
+func TestQuantizeStrength(t *testing.T) {
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 50}, t)
+
+	assertQuantized(quantizer, []uint64{10, 87, 96, 101}, []uint64{5, 92, 96, 98}, t)
+
+	quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 0}, t)
+
+	assertQuantized(quantizer, []uint64{10, 87}, []uint64{10, 87}, t)
+}
+
+// Swing should delay every second grid line.
+func TestQuantizeSwing(t *testing.T) {
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 8, Strength: 100, Swing: 50}, t)
+
+	// Lines at 0, 72, 96, 168, 192.
+	assertQuantized(quantizer, []uint64{30, 50, 80, 90, 150, 185}, []uint64{0, 72, 72, 96, 168, 192}, t)
+}
+
+// Only times within the window should move.
+func TestQuantizeWindow(t *testing.T) {
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 100, Window: 25}, t)
+
+	assertQuantized(quantizer, []uint64{10, 24, 25, 40, 71, 72, 90}, []uint64{0, 0, 25, 40, 71, 96, 96}, t)
+}
+
+// Grids that don't fit the ticks per quarter note, and percentages out of range, are errors.
+func TestQuantizeInvalidSettings(t *testing.T) {
+	var header = HeaderData{TimeFormat: MetricalTimeFormat, TicksPerQuarterNote: 96}
+
+	for _, settings := range []QuantizeSettings{
+		{Grid: 0, Strength: 100},
+		{Grid: 256, Strength: 100},
+		{Grid: 1, Triplet: true, Strength: 100},
+		{Grid: 16, Strength: 101},
+		{Grid: 16, Strength: -1},
+		{Grid: 16, Strength: 100, Swing: 100},
+		{Grid: 16, Strength: 100, Window: 101},
+	} {
+		var _, err = NewQuantizer(header, settings)
+		assertError(err, InvalidQuantizeSettings, t)
+	}
+
+	var _, err = NewQuantizer(HeaderData{TimeFormat: TimeCodeTimeFormat}, QuantizeSettings{Grid: 16, Strength: 100})
+	assertError(err, NotMetricalTimeFormat, t)
+
+	// No ticks per quarter note would make a grid with no spacing.
+	_, err = NewQuantizer(HeaderData{TimeFormat: MetricalTimeFormat, TicksPerQuarterNote: 0}, QuantizeSettings{Grid: 16, Strength: 100})
+	assertError(err, NotMetricalTimeFormat, t)
+}
+
+// Notes should keep their durations, unless the ends are quantized.
+func TestQuantizeNotes(t *testing.T) {
+	var notes = []Note{
+		{Pitch: 60, StartTick: 10, DurationTicks: 80},
+		{Pitch: 62, StartTick: 100, DurationTicks: 10},
+	}
+
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 100}, t)
+	var result = quantizer.QuantizeNotes(notes)
+
+	assertNote(result[0], 60, 0, 80, 0, 0, t)
+	assertNote(result[1], 62, 96, 10, 0, 0, t)
+
+	quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 100, QuantizeEnds: true}, t)
+	result = quantizer.QuantizeNotes(notes)
+
+	// The second note would end where it starts, so is made one grid spacing long.
+	assertNote(result[0], 60, 0, 96, 0, 0, t)
+	assertNote(result[1], 62, 96, 96, 0, 0, t)
+
+	// The original is unchanged.
+	assertIntsEqual(int(notes[0].StartTick), 10, t)
+}
+
+// Quantizing a File should move NoteOns with their NoteOffs and keep the events in a valid order.
+func TestQuantizeFile(t *testing.T) {
+	var file = File{Tracks: []Track{{Events: []Event{
+		&NoteOn{EventContext: at(0), Pitch: 60, Velocity: 100},
+		&ControlChange{EventContext: at(50)},
+		&NoteOn{EventContext: at(95), Pitch: 60, Velocity: 90},
+		&NoteOff{EventContext: at(97), Pitch: 62},
+		&NoteOn{EventContext: at(100), Pitch: 60, Velocity: 0},
+		&NoteOff{EventContext: at(190), Pitch: 60},
+		&EndOfTrack{EventContext: at(190)},
+	}}}}
+
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 100, QuantizeEnds: true}, t)
+	var result = quantizer.QuantizeFile(&file, FIFONoteMatching)
+	var events = result.Tracks[0].Events
+
+	assertTrackTimes(result.Tracks[0], 0, []uint64{0, 50, 96, 96, 97, 192, 192}, t)
+
+	// The end of the first note comes before the start of the second.
+	assertUint8sEqual(events[2].(*NoteOn).Velocity, 0, t)
+	assertUint8sEqual(events[3].(*NoteOn).Velocity, 90, t)
+
+	// Orphaned NoteOffs aren't moved.
+	assertUint8sEqual(events[4].(*NoteOff).Pitch, 62, t)
+
+	var _, isEndOfTrack = events[6].(*EndOfTrack)
+	assertTrue(isEndOfTrack, t)
+
+	// The notes are the same, moved to the grid.
+	var notes, _ = PairNotes(result.Tracks[0], 0, FIFONoteMatching)
+	assertIntsEqual(len(notes), 2, t)
+	assertNote(notes[0], 60, 0, 96, 100, 0, t)
+	assertNote(notes[1], 60, 96, 96, 90, 0, t)
+
+	// The original is unchanged.
+	assertIntsEqual(int(file.Tracks[0].Events[2].Context().AbsoluteTicks), 95, t)
+}
+
+// The EndOfTrack should move after notes moved past it, and notes should be at least one tick long.
+func TestQuantizeFileEndOfTrack(t *testing.T) {
+	var file = File{Tracks: []Track{{Events: []Event{
+		&NoteOn{EventContext: at(80), Pitch: 60, Velocity: 100},
+		&NoteOff{EventContext: at(80), Pitch: 60},
+		&EndOfTrack{EventContext: at(80)},
+	}}}}
+
+	var quantizer = newTestQuantizer(QuantizeSettings{Grid: 4, Strength: 100}, t)
+	var result = quantizer.QuantizeFile(&file, FIFONoteMatching)
+
+	assertTrackTimes(result.Tracks[0], 0, []uint64{96, 97, 97}, t)
+}
@@ -0,0 +1,92 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Readers for the lexer.
+ * The lexer reads from a ReadSeeker. These let it read from other kinds of input.
+ */
+
+package midi
+
+import (
+	"bufio"
+	"io"
+)
+
+// forwardSeeker is a ReadSeeker for input that can't seek, like pipes and HTTP bodies.
+// It keeps track of the position, and seeks forward by discarding input. It can't seek backward.
+// The input is buffered, and it's also a ByteReader, so reading from it doesn't allocate.
+type forwardSeeker struct {
+	reader   *bufio.Reader
+	position int64
+}
+
+// newForwardSeeker creates a new forwardSeeker reading from the given Reader.
+func newForwardSeeker(reader io.Reader) *forwardSeeker {
+	return &forwardSeeker{reader: bufio.NewReader(reader)}
+}
+
+// Read fills the given buffer, returning the number of bytes and an error.
+// Unlike some Readers, it will only return fewer bytes than asked for at the end of the input.
+func (reader *forwardSeeker) Read(p []byte) (n int, err error) {
+	n, err = io.ReadFull(reader.reader, p)
+	reader.position += int64(n)
+
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// ReadByte reads a single byte.
+func (reader *forwardSeeker) ReadByte() (byte, error) {
+	octet, err := reader.reader.ReadByte()
+
+	if err == nil {
+		reader.position++
+	}
+
+	return octet, err
+}
+
+// Seek sets the offset for the next Read, interpreted according to the value of `whence`:
+// 0 means relative to the origin of the file, 1 means relative to the current offset.
+// The offset can't be before the current offset, or relative to the end.
+// Seek returns the new offset and an Error, if any.
+func (reader *forwardSeeker) Seek(offset int64, whence int) (ret int64, err error) {
+	var target int64
+
+	switch whence {
+	case 0:
+		target = offset
+	case 1:
+		target = reader.position + offset
+	default:
+		return reader.position, CannotSeek
+	}
+
+	if target < reader.position {
+		return reader.position, CannotSeek
+	}
+
+	// Discard takes an int, so skip in pieces in case it's a long way.
+	for reader.position < target && err == nil {
+		var skip = target - reader.position
+		if skip > 1<<30 {
+			skip = 1 << 30
+		}
+
+		var skipped int
+		skipped, err = reader.reader.Discard(int(skip))
+		reader.position += int64(skipped)
+	}
+
+	return reader.position, err
+}
@@ -0,0 +1,192 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for readers.
+ * Check that the lexer behaves the same whatever it's reading from.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+// The forwardSeeker should read fully even if the underlying reader returns less.
+func TestForwardSeekerReads(t *testing.T) {
+	var reader = newForwardSeeker(iotest.OneByteReader(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04, 0x05})))
+
+	var data = make([]byte, 3)
+	count, err := reader.Read(data)
+
+	assertIntsEqual(count, 3, t)
+	assertNoError(err, t)
+	assertBytesEqual(data, []byte{0x01, 0x02, 0x03}, t)
+
+	// Only 2 left.
+	count, _ = reader.Read(data)
+	assertIntsEqual(count, 2, t)
+	assertIntsEqual(int(reader.position), 5, t)
+}
+
+// The forwardSeeker should seek forwards only.
+func TestForwardSeekerSeeks(t *testing.T) {
+	var reader = newForwardSeeker(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}))
+
+	position, err := reader.Seek(0, 1)
+	assertNoError(err, t)
+	assertIntsEqual(int(position), 0, t)
+
+	position, err = reader.Seek(2, 1)
+	assertNoError(err, t)
+	assertIntsEqual(int(position), 2, t)
+
+	position, err = reader.Seek(4, 0)
+	assertNoError(err, t)
+	assertIntsEqual(int(position), 4, t)
+
+	value, err := parseUint8(reader)
+	assertNoError(err, t)
+	assertUint8sEqual(value, 0x05, t)
+
+	_, err = reader.Seek(1, 0)
+	assertError(err, CannotSeek, t)
+
+	_, err = reader.Seek(0, 2)
+	assertError(err, CannotSeek, t)
+}
+
+// Lexing from a Reader should give the same callbacks as a ReadSeeker.
+func TestLexFromReader(t *testing.T) {
+	var data = testMidiFile()
+
+	var fromReadSeeker = new(CountingLexerCallback)
+	err = NewMidiLexer(NewMockReadSeeker(&data), fromReadSeeker).Lex()
+	assertNoError(err, t)
+
+	// A reader that returns one byte at a time, like a slow pipe.
+	var fromReader = new(CountingLexerCallback)
+	lexer = NewMidiLexerFromReader(iotest.OneByteReader(bytes.NewReader(data)), fromReader)
+	lexer.SetMode(StrictMode)
+	err = lexer.Lex()
+	assertNoError(err, t)
+
+	assertIntsEqual(fromReader.track, 3, t)
+	assertIntsEqual(fromReader.noteOn, 3, t)
+	assertIntsEqual(fromReader.finished, 1, t)
+
+	if !reflect.DeepEqual(fromReadSeeker, fromReader) {
+		t.Fatal(fromReadSeeker, " != ", fromReader)
+	}
+}
+
+// A nil Reader should be an error.
+func TestLexFromNilReader(t *testing.T) {
+	err = NewMidiLexerFromReader(nil, new(CountingLexerCallback)).Lex()
+
+	assertError(err, NoReadSeeker, t)
+}
+
+// Lexing from bytes should give the same callbacks as a ReadSeeker.
+func TestLexFromBytes(t *testing.T) {
+	var data = testMidiFile()
+
+	var fromReadSeeker = new(CountingLexerCallback)
+	err = NewMidiLexer(NewMockReadSeeker(&data), fromReadSeeker).Lex()
+	assertNoError(err, t)
+
+	var fromBytes = new(CountingLexerCallback)
+	err = NewMidiLexerFromBytes(data, fromBytes).Lex()
+	assertNoError(err, t)
+
+	if !reflect.DeepEqual(fromReadSeeker, fromBytes) {
+		t.Fatal(fromReadSeeker, " != ", fromBytes)
+	}
+}
+
+// manyNotesMidiFile returns an SMF file with one track of the given number of NoteOn and NoteOff events.
+func manyNotesMidiFile(notes int) []byte {
+	var track []byte
+
+	for i := 0; i < notes; i++ {
+		var pitch = byte(i % 128)
+		track = append(track, 0x00, 0x90, pitch, 0x40, 0x60, 0x80, pitch, 0x40)
+	}
+
+	track = append(track, 0x00, 0xFF, 0x2F, 0x00)
+
+	var data = []byte{
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x60,
+		0x4D, 0x54, 0x72, 0x6B, byte(len(track) >> 24), byte(len(track) >> 16), byte(len(track) >> 8), byte(len(track))}
+
+	return append(data, track...)
+}
+
+// Lexing channel messages from bytes or a Reader shouldn't allocate, however many there are.
+func TestLexDoesNotAllocatePerEvent(t *testing.T) {
+	var few = manyNotesMidiFile(10)
+	var many = manyNotesMidiFile(1000)
+	var callback = new(MockLexerCallback)
+
+	var fewAllocs = testing.AllocsPerRun(10, func() { NewMidiLexerFromBytes(few, callback).Lex() })
+	var manyAllocs = testing.AllocsPerRun(10, func() { NewMidiLexerFromBytes(many, callback).Lex() })
+
+	if fewAllocs != manyAllocs {
+		t.Fatal("Allocations from bytes for 10 notes ", fewAllocs, " vs 1000 notes ", manyAllocs)
+	}
+
+	fewAllocs = testing.AllocsPerRun(10, func() { NewMidiLexerFromReader(bytes.NewReader(few), callback).Lex() })
+	manyAllocs = testing.AllocsPerRun(10, func() { NewMidiLexerFromReader(bytes.NewReader(many), callback).Lex() })
+
+	if fewAllocs != manyAllocs {
+		t.Fatal("Allocations from Reader for 10 notes ", fewAllocs, " vs 1000 notes ", manyAllocs)
+	}
+}
+
+// Benchmark lexing from a ReadSeeker that isn't a ByteReader, like a file.
+func BenchmarkLexReadSeeker(b *testing.B) {
+	var data = manyNotesMidiFile(1000)
+	var callback = new(MockLexerCallback)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		NewMidiLexer(NewMockReadSeeker(&data), callback).Lex()
+	}
+}
+
+// Benchmark lexing from a Reader.
+func BenchmarkLexReader(b *testing.B) {
+	var data = manyNotesMidiFile(1000)
+	var callback = new(MockLexerCallback)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		NewMidiLexerFromReader(bytes.NewReader(data), callback).Lex()
+	}
+}
+
+// Benchmark lexing from bytes.
+func BenchmarkLexBytes(b *testing.B) {
+	var data = manyNotesMidiFile(1000)
+	var callback = new(MockLexerCallback)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		NewMidiLexerFromBytes(data, callback).Lex()
+	}
+}
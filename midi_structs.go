@@ -19,6 +19,25 @@ type ChunkHeader struct {
 	Length    uint32
 }
 
+// The position of a track event.
+// Supplied to MidiLexerContextCallback before each track event.
+type EventContext struct {
+	// Index of the track (MTrk chunk) in the file, starting at 0.
+	Track int
+
+	// Ticks since the previous event in the track. This is the time value passed to the event callback.
+	DeltaTicks uint32
+
+	// Ticks since the start of the track.
+	AbsoluteTicks uint64
+
+	// Byte offset of the start of the event in the file.
+	Offset int64
+
+	// The event had no status byte in the file, because it was the same as the one before.
+	RunningStatus bool
+}
+
 // Header data
 type HeaderData struct {
 	Format    uint16
@@ -28,9 +47,25 @@ type HeaderData struct {
 	TimeFormat uint
 
 	// Used if TimeCodeTimeFormat
-	// Currently data is not un-packed.
+	// The raw division data, which is un-packed into FrameRate and TicksPerFrame.
 	TimeFormatData uint16
 
+	// Used if TimeCodeTimeFormat
+	FrameRate     SMPTEFrameRate
+	TicksPerFrame uint8
+
 	// Used if MetricalTimeFormat
 	TicksPerQuarterNote uint16
 }
+
+// An SMPTE time code position.
+type SMPTETimecode struct {
+	FrameRate SMPTEFrameRate
+	Hours     uint8
+	Minutes   uint8
+	Seconds   uint8
+	Frames    uint8
+
+	// Ticks into the frame, out of HeaderData.TicksPerFrame.
+	SubFrames uint8
+}
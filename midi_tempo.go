@@ -0,0 +1,165 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * The tempo map.
+ * Converts between ticks and real time, taking account of tempo changes.
+ */
+
+package midi
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// The tempo until the first Tempo event, 120 beats per minute.
+const DefaultMicrosecondsPerCrotchet = 500000
+
+// TempoMap converts between absolute ticks and the time since the start of the file.
+type TempoMap struct {
+	ticksPerQuarterNote uint16
+
+	// In order of ticks. The first is always at 0.
+	changes []tempoChange
+}
+
+// A tempo change in the TempoMap.
+type tempoChange struct {
+	ticks                   uint64
+	microsecondsPerCrotchet uint32
+
+	// The time of the change since the start.
+	time time.Duration
+}
+
+// NewTempoMap creates a TempoMap from the Tempo events in all the tracks of a File.
+// The File must be in MetricalTimeFormat, and not format 2, as each track has its own time. See FlattenSequence.
+func NewTempoMap(file *File) (*TempoMap, error) {
+	if file.Format == SequentialTracks {
+		return nil, WrongFormat
+	}
+
+	var tempos []*Tempo
+
+	for event := range file.MergedEvents() {
+		if tempo, ok := event.(*Tempo); ok {
+			tempos = append(tempos, tempo)
+		}
+	}
+
+	return NewTempoMapFromEvents(file.HeaderData, tempos)
+}
+
+// NewTempoMapFromEvents creates a TempoMap from Tempo events, in time order.
+// Only the last of several Tempo events at the same time counts.
+func NewTempoMapFromEvents(header HeaderData, tempos []*Tempo) (*TempoMap, error) {
+	if header.TimeFormat != MetricalTimeFormat || header.TicksPerQuarterNote == 0 {
+		return nil, NotMetricalTimeFormat
+	}
+
+	var tempoMap = &TempoMap{ticksPerQuarterNote: header.TicksPerQuarterNote}
+	tempoMap.changes = []tempoChange{{ticks: 0, microsecondsPerCrotchet: DefaultMicrosecondsPerCrotchet}}
+
+	for _, tempo := range tempos {
+		if tempo.MicrosecondsPerCrotchet == 0 {
+			return nil, InvalidTempo
+		}
+
+		var last = &tempoMap.changes[len(tempoMap.changes)-1]
+
+		if tempo.AbsoluteTicks < last.ticks {
+			return nil, EventsOutOfOrder
+		}
+
+		if tempo.AbsoluteTicks == last.ticks {
+			last.microsecondsPerCrotchet = tempo.MicrosecondsPerCrotchet
+			continue
+		}
+
+		var change = tempoChange{ticks: tempo.AbsoluteTicks, microsecondsPerCrotchet: tempo.MicrosecondsPerCrotchet}
+		change.time = last.time + last.duration(change.ticks-last.ticks, tempoMap.ticksPerQuarterNote)
+
+		tempoMap.changes = append(tempoMap.changes, change)
+	}
+
+	return tempoMap, nil
+}
+
+// Duration returns the time of an absolute tick since the start, rounded up to the nanosecond.
+func (tempoMap *TempoMap) Duration(ticks uint64) time.Duration {
+	var change = tempoMap.changeAtTicks(ticks)
+
+	return change.time + change.duration(ticks-change.ticks, tempoMap.ticksPerQuarterNote)
+}
+
+// Ticks returns the absolute tick at a time since the start, i.e. the last tick at or before that time.
+func (tempoMap *TempoMap) Ticks(duration time.Duration) uint64 {
+	if duration <= 0 {
+		return 0
+	}
+
+	var i = sort.Search(len(tempoMap.changes), func(i int) bool {
+		return tempoMap.changes[i].time > duration
+	}) - 1
+
+	var change = tempoMap.changes[i]
+
+	return change.ticks + mulDiv(uint64(duration-change.time), uint64(tempoMap.ticksPerQuarterNote), uint64(change.microsecondsPerCrotchet)*1000, false)
+}
+
+// MicrosecondsPerCrotchet returns the tempo at an absolute tick.
+func (tempoMap *TempoMap) MicrosecondsPerCrotchet(ticks uint64) uint32 {
+	return tempoMap.changeAtTicks(ticks).microsecondsPerCrotchet
+}
+
+// BPM returns the tempo at an absolute tick in beats (crotchets) per minute, without rounding.
+func (tempoMap *TempoMap) BPM(ticks uint64) float64 {
+	return 60000000 / float64(tempoMap.MicrosecondsPerCrotchet(ticks))
+}
+
+// changeAtTicks returns the tempo change in effect at an absolute tick.
+func (tempoMap *TempoMap) changeAtTicks(ticks uint64) tempoChange {
+	var i = sort.Search(len(tempoMap.changes), func(i int) bool {
+		return tempoMap.changes[i].ticks > ticks
+	}) - 1
+
+	return tempoMap.changes[i]
+}
+
+// duration returns the time taken by a number of ticks at the tempo of the change, rounded up to the nanosecond.
+func (change tempoChange) duration(ticks uint64, ticksPerQuarterNote uint16) time.Duration {
+	var nanoseconds = mulDiv(ticks, uint64(change.microsecondsPerCrotchet)*1000, uint64(ticksPerQuarterNote), true)
+
+	if nanoseconds > math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(nanoseconds)
+}
+
+// mulDiv returns a * b / c without overflowing in between, rounded down or up.
+// If the result is too big it's the biggest uint64.
+func mulDiv(a uint64, b uint64, c uint64, roundUp bool) uint64 {
+	high, low := bits.Mul64(a, b)
+
+	if high >= c {
+		return math.MaxUint64
+	}
+
+	quotient, remainder := bits.Div64(high, low, c)
+
+	if roundUp && remainder > 0 && quotient < math.MaxUint64 {
+		quotient++
+	}
+
+	return quotient
+}
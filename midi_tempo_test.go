@@ -0,0 +1,119 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the tempo map.
+ */
+
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+// Without any tempo events, the tempo is 120 bpm.
+func TestTempoMapDefault(t *testing.T) {
+	tempoMap, err := NewTempoMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, nil)
+	assertNoError(err, t)
+
+	assertIntsEqual(int(tempoMap.Duration(0)), 0, t)
+	assertIntsEqual(int(tempoMap.Duration(96)), int(500*time.Millisecond), t)
+	assertIntsEqual(int(tempoMap.Duration(96*120)), int(time.Minute), t)
+	assertIntsEqual(int(tempoMap.Ticks(time.Minute)), 96*120, t)
+	assertTrue(tempoMap.BPM(1000) == 120, t)
+}
+
+// Times should take account of each tempo change.
+func TestTempoMapChanges(t *testing.T) {
+	var tempos = []*Tempo{
+		// 60 bpm, one beat per second.
+		{EventContext: at(0), MicrosecondsPerCrotchet: 1000000},
+
+		// 180 bpm, a third of a second per beat.
+		{EventContext: at(96 * 2), MicrosecondsPerCrotchet: 333333},
+
+		// 100 bpm. The one after at the same time is ignored.
+		{EventContext: at(96 * 5), MicrosecondsPerCrotchet: 0x1234},
+		{EventContext: at(96 * 5), MicrosecondsPerCrotchet: 600000},
+	}
+
+	tempoMap, err := NewTempoMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, tempos)
+	assertNoError(err, t)
+
+	assertIntsEqual(int(tempoMap.Duration(96)), int(time.Second), t)
+	assertIntsEqual(int(tempoMap.Duration(96*2)), int(2*time.Second), t)
+	assertIntsEqual(int(tempoMap.Duration(96*3)), int(2*time.Second+333333*time.Microsecond), t)
+	assertIntsEqual(int(tempoMap.Duration(96*5)), int(2*time.Second+999999*time.Microsecond), t)
+	assertIntsEqual(int(tempoMap.Duration(96*6)), int(3*time.Second+599999*time.Microsecond), t)
+
+	assertIntsEqual(int(tempoMap.Ticks(time.Second)), 96, t)
+	assertIntsEqual(int(tempoMap.Ticks(2*time.Second+333333*time.Microsecond)), 96*3, t)
+	assertIntsEqual(int(tempoMap.Ticks(3*time.Second+599999*time.Microsecond)), 96*6, t)
+
+	// Just before a tick is the tick before.
+	assertIntsEqual(int(tempoMap.Ticks(time.Second-1)), 95, t)
+	assertIntsEqual(int(tempoMap.Ticks(-time.Second)), 0, t)
+
+	// Fractional tempo.
+	assertUint32Equal(tempoMap.MicrosecondsPerCrotchet(96*2), 333333, t)
+	assertTrue(tempoMap.BPM(96*3) > 180.00018 && tempoMap.BPM(96*3) < 180.00019, t)
+	assertTrue(tempoMap.BPM(96*5) == 100, t)
+	assertTrue(tempos[1].BPM() == tempoMap.BPM(96*2), t)
+
+	// Every tick should convert back to itself.
+	for ticks := uint64(0); ticks < 96*10; ticks++ {
+		assertIntsEqual(int(tempoMap.Ticks(tempoMap.Duration(ticks))), int(ticks), t)
+	}
+}
+
+// The tempo map of a file should use tempo events from any track.
+func TestNewTempoMap(t *testing.T) {
+	file, err := ReadFileFromBytes(testMidiFile())
+	assertNoError(err, t)
+
+	file.Tracks[1].Events = append([]Event{&Tempo{EventContext: at(0x30), MicrosecondsPerCrotchet: 250000}}, file.Tracks[1].Events...)
+
+	tempoMap, err := NewTempoMap(file)
+	assertNoError(err, t)
+
+	assertIntsEqual(int(tempoMap.Duration(0x60)), int(375*time.Millisecond), t)
+
+	// Not for time code files, or format 2.
+	file.TimeFormat = TimeCodeTimeFormat
+	_, err = NewTempoMap(file)
+	assertError(err, NotMetricalTimeFormat, t)
+
+	file.Format = SequentialTracks
+	_, err = NewTempoMap(file)
+	assertError(err, WrongFormat, t)
+}
+
+// Bad tempo events should be errors.
+func TestTempoMapErrors(t *testing.T) {
+	_, err := NewTempoMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, []*Tempo{{}})
+	assertError(err, InvalidTempo, t)
+
+	_, err = NewTempoMapFromEvents(HeaderData{TicksPerQuarterNote: 96}, []*Tempo{{EventContext: at(10), MicrosecondsPerCrotchet: 1}, {EventContext: at(5), MicrosecondsPerCrotchet: 1}})
+	assertError(err, EventsOutOfOrder, t)
+
+	_, err = NewTempoMapFromEvents(HeaderData{}, nil)
+	assertError(err, NotMetricalTimeFormat, t)
+}
+
+// Long files shouldn't overflow, even though ticks * microseconds * 1000 doesn't fit in 64 bits.
+func TestTempoMapLong(t *testing.T) {
+	tempoMap, err := NewTempoMapFromEvents(HeaderData{TicksPerQuarterNote: 960}, []*Tempo{{MicrosecondsPerCrotchet: 0xFFFFFF}})
+	assertNoError(err, t)
+
+	// About 55 years.
+	var duration = tempoMap.Duration(100000000000)
+	assertIntsEqual(int(duration), 1747626562500000000, t)
+	assertIntsEqual(int(tempoMap.Ticks(duration)), 100000000000, t)
+}
@@ -103,3 +103,30 @@ func assertStringsEqual(a string, b string, test *testing.T) {
 		test.Fatal(a, " != ", b)
 	}
 }
+
+// testMidiFile returns a small but complete SMF file, for tests that lex whole files.
+func testMidiFile() []byte {
+	return []byte{
+		// MThd, format 1, 2 tracks, 96 ticks per quarter note.
+		0x4D, 0x54, 0x68, 0x64, 0x00, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x60,
+
+		// MTrk, conductor track.
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x13,
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // Tempo 120 bpm
+		0x00, 0xFF, 0x58, 0x04, 0x04, 0x02, 0x18, 0x08, // Time signature 4/4
+		0x00, 0xFF, 0x2F, 0x00, // End of track
+
+		// An unknown chunk, which should be skipped.
+		0x58, 0x59, 0x5A, 0x5A, 0x00, 0x00, 0x00, 0x03, 0x01, 0x02, 0x03,
+
+		// MTrk, notes.
+		0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x00, 0x22,
+		0x00, 0xFF, 0x03, 0x04, 0x50, 0x69, 0x61, 0x6E, // Track name "Pian"
+		0x00, 0xF0, 0x05, 0x7E, 0x7F, 0x09, 0x01, 0xF7, // GM System On
+		0x00, 0x90, 0x3C, 0x40, // NoteOn C
+		0x00, 0x40, 0x40, // NoteOn E, running status
+		0x60, 0x3C, 0x00, // NoteOn C velocity 0, running status
+		0x00, 0x80, 0x40, 0x40, // NoteOff E
+		0x00, 0xFF, 0x2F, 0x00, // End of track
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Functions for SMPTE time code.
+ * Files with a TimeCodeTimeFormat division count time in fractions of a frame rather than fractions of a crotchet.
+ */
+
+package midi
+
+import (
+	"fmt"
+)
+
+// 29.97 drop frame drops frame numbers 0 and 1 at the start of every minute except every tenth minute.
+const (
+	dropFramesPerMinute    = 2
+	dropFramesPerTenMinute = 10*30*60 - 9*dropFramesPerMinute
+	dropFramesPerOneMinute = 30*60 - dropFramesPerMinute
+)
+
+// validFrameRate returns true if the frame rate is one that SMPTE allows.
+func validFrameRate(frameRate SMPTEFrameRate) bool {
+	switch frameRate {
+	case SMPTE24, SMPTE25, SMPTE30DropFrame, SMPTE30:
+		return true
+	}
+
+	return false
+}
+
+// FramesPerSecond returns the real number of frames per second.
+// For 30 drop frame this is 29.97 (actually 30000/1001).
+func (frameRate SMPTEFrameRate) FramesPerSecond() float64 {
+	if frameRate == SMPTE30DropFrame {
+		return 30000.0 / 1001.0
+	}
+
+	return float64(frameRate)
+}
+
+// nominalFramesPerSecond returns the number of frame numbers in a second of time code.
+// For 30 drop frame this is 30.
+func (frameRate SMPTEFrameRate) nominalFramesPerSecond() uint64 {
+	if frameRate == SMPTE30DropFrame {
+		return 30
+	}
+
+	return uint64(frameRate)
+}
+
+// TicksToSeconds converts a tick count to seconds for a file with a TimeCodeTimeFormat division.
+func (header HeaderData) TicksToSeconds(ticks uint64) (float64, error) {
+	if header.TimeFormat != TimeCodeTimeFormat {
+		return 0, NotTimeCodeTimeFormat
+	}
+
+	if header.TicksPerFrame == 0 {
+		return 0, UnsupportedTimeCodeFormat
+	}
+
+	return float64(ticks) / float64(header.TicksPerFrame) / header.FrameRate.FramesPerSecond(), nil
+}
+
+// TicksToTimecode converts a tick count to an SMPTE time code for a file with a TimeCodeTimeFormat division.
+// Hours wrap around after 24, as time code does.
+func (header HeaderData) TicksToTimecode(ticks uint64) (SMPTETimecode, error) {
+	var timecode SMPTETimecode
+
+	if header.TimeFormat != TimeCodeTimeFormat {
+		return timecode, NotTimeCodeTimeFormat
+	}
+
+	if header.TicksPerFrame == 0 {
+		return timecode, UnsupportedTimeCodeFormat
+	}
+
+	var frame = ticks / uint64(header.TicksPerFrame)
+
+	// Drop frame numbering skips some frame numbers, so add them back in to get the frame number.
+	if header.FrameRate == SMPTE30DropFrame {
+		var tens = frame / dropFramesPerTenMinute
+		var remainder = frame % dropFramesPerTenMinute
+
+		frame += 9 * dropFramesPerMinute * tens
+
+		if remainder > dropFramesPerMinute {
+			frame += dropFramesPerMinute * ((remainder - dropFramesPerMinute) / dropFramesPerOneMinute)
+		}
+	}
+
+	var fps = header.FrameRate.nominalFramesPerSecond()
+
+	timecode.FrameRate = header.FrameRate
+	timecode.SubFrames = uint8(ticks % uint64(header.TicksPerFrame))
+	timecode.Frames = uint8(frame % fps)
+	timecode.Seconds = uint8((frame / fps) % 60)
+	timecode.Minutes = uint8((frame / fps / 60) % 60)
+	timecode.Hours = uint8((frame / fps / 3600) % 24)
+
+	return timecode, nil
+}
+
+// String formats the time code as HH:MM:SS:FF.SS, using a semicolon before the frames for drop frame.
+func (timecode SMPTETimecode) String() string {
+	var separator = ":"
+	if timecode.FrameRate == SMPTE30DropFrame {
+		separator = ";"
+	}
+
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d.%02d", timecode.Hours, timecode.Minutes, timecode.Seconds, separator, timecode.Frames, timecode.SubFrames)
+}
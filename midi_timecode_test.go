@@ -0,0 +1,81 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for SMPTE time code functions.
+ */
+
+package midi
+
+import (
+	"testing"
+)
+
+// 25 fps with 40 ticks per frame means 1000 ticks per second.
+var header25fps = HeaderData{TimeFormat: TimeCodeTimeFormat, FrameRate: SMPTE25, TicksPerFrame: 40}
+
+// 29.97 drop frame with 4 ticks per frame.
+var header30Drop = HeaderData{TimeFormat: TimeCodeTimeFormat, FrameRate: SMPTE30DropFrame, TicksPerFrame: 4}
+
+// TicksToSeconds should convert using the frame rate and ticks per frame.
+func TestTicksToSeconds(t *testing.T) {
+	seconds, err := header25fps.TicksToSeconds(1500)
+	assertNoError(err, t)
+
+	if seconds != 1.5 {
+		t.Fatal("Expected 1.5 got ", seconds)
+	}
+
+	// 30 frames at 29.97 is slightly over a second.
+	seconds, err = header30Drop.TicksToSeconds(30 * 4)
+	assertNoError(err, t)
+
+	if seconds < 1.000999 || seconds > 1.001001 {
+		t.Fatal("Expected 1.001 got ", seconds)
+	}
+
+	// Metrical files can't be converted without a tempo.
+	_, err = HeaderData{TimeFormat: MetricalTimeFormat, TicksPerQuarterNote: 96}.TicksToSeconds(96)
+	assertError(err, NotTimeCodeTimeFormat, t)
+}
+
+// TicksToTimecode should convert to non-drop time codes.
+func TestTicksToTimecode(t *testing.T) {
+	// 1 hour, 2 minutes, 3 seconds, 4 frames and 5 ticks.
+	var ticks uint64 = ((3600+2*60+3)*25+4)*40 + 5
+
+	timecode, err := header25fps.TicksToTimecode(ticks)
+	assertNoError(err, t)
+
+	expected := SMPTETimecode{FrameRate: SMPTE25, Hours: 1, Minutes: 2, Seconds: 3, Frames: 4, SubFrames: 5}
+	if timecode != expected {
+		t.Fatal(timecode, " != ", expected)
+	}
+
+	assertStringsEqual(timecode.String(), "01:02:03:04.05", t)
+}
+
+// TicksToTimecode should skip dropped frame numbers for 29.97 drop frame.
+func TestTicksToDropFrameTimecode(t *testing.T) {
+	// Frame counts and the time codes they should have.
+	frames := []uint64{0, 1799, 1800, 17981, 17982}
+	expected := []string{
+		"00:00:00;00.00",
+		"00:00:59;29.00",
+		"00:01:00;02.00", // Frames 0 and 1 dropped.
+		"00:09:59;29.00",
+		"00:10:00;00.00", // Nothing dropped on tenth minute.
+	}
+
+	for i := 0; i < len(frames); i++ {
+		timecode, err := header30Drop.TicksToTimecode(frames[i] * 4)
+		assertNoError(err, t)
+		assertStringsEqual(timecode.String(), expected[i], t)
+	}
+}
@@ -24,6 +24,20 @@ const (
 	TimeCodeTimeFormat = iota
 )
 
+// SMPTE frame rates, in frames per second.
+// Supplied to SMPTEOffset.
+const (
+	SMPTE24          = 24
+	SMPTE25          = 25
+	SMPTE30DropFrame = 29
+	SMPTE30          = 30
+)
+
+type SMPTEFrameRate uint8
+
+// The frame rates encoded in the top bits of the SMPTE Offset hours byte.
+var smpteOffsetFrameRates = [4]SMPTEFrameRate{SMPTE24, SMPTE25, SMPTE30DropFrame, SMPTE30}
+
 // Supplied to KeySignature
 const (
 	MajorMode = 0
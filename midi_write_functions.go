@@ -0,0 +1,194 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Functions for writing actual MIDI data in the various formats that crop up.
+ * These are the reverse of the parse functions in midi_functions.go.
+ */
+
+package midi
+
+import (
+	"io"
+)
+
+// writeBigEndian writes the lowest length bytes of a value as a big-endian integer.
+func writeBigEndian(writer io.Writer, value uint32, length int) error {
+	var buffer [4]byte
+
+	for i := length - 1; i >= 0; i-- {
+		buffer[i] = byte(value)
+		value >>= 8
+	}
+
+	_, err := writer.Write(buffer[:length])
+
+	return err
+}
+
+// writeUint32 writes a 32 bit uint.
+func writeUint32(writer io.Writer, value uint32) error {
+	return writeBigEndian(writer, value, 4)
+}
+
+// writeUint24 writes a 24 bit uint.
+func writeUint24(writer io.Writer, value uint32) error {
+	return writeBigEndian(writer, value, 3)
+}
+
+// writeUint16 writes a 16 bit uint.
+func writeUint16(writer io.Writer, value uint16) error {
+	return writeBigEndian(writer, uint32(value), 2)
+}
+
+// writeUint8 writes an 8 bit uint.
+func writeUint8(writer io.Writer, value uint8) error {
+	return writeBigEndian(writer, uint32(value), 1)
+}
+
+// writeUint7 writes a data byte, which must have the high bit clear.
+func writeUint7(writer io.Writer, value uint8) error {
+	if value&0x80 != 0 {
+		return BadDataByte
+	}
+
+	return writeUint8(writer, value)
+}
+
+// writeTwoUint7 writes two data bytes.
+func writeTwoUint7(writer io.Writer, first uint8, second uint8) error {
+	var err = writeUint7(writer, first)
+
+	if err != nil {
+		return err
+	}
+
+	return writeUint7(writer, second)
+}
+
+// encodePitchWheelValue returns the two data bytes of a pitch wheel value relative to the centre.
+// The reverse of pitchWheelValue.
+func encodePitchWheelValue(relative int16) (leastSignificant uint8, mostSignificant uint8) {
+	var absolute = uint16(int32(relative)+0x2000) & 0x3FFF
+
+	return uint8(absolute & 0x7F), uint8(absolute >> 7)
+}
+
+// writePitchWheelValue writes a pitch wheel value relative to the centre.
+func writePitchWheelValue(writer io.Writer, relative int16) error {
+	leastSignificant, mostSignificant := encodePitchWheelValue(relative)
+
+	return writeTwoUint7(writer, leastSignificant, mostSignificant)
+}
+
+// The largest value that fits in a variable length value.
+const maxVarLength = 0x0FFFFFFF
+
+// writeVarLength writes a variable length value, at most maxVarLength.
+func writeVarLength(writer io.Writer, value uint32) error {
+	if value > maxVarLength {
+		return VarLengthTooBig
+	}
+
+	var buffer [4]byte
+	var i = len(buffer) - 1
+
+	// The last byte has the high bit clear, the ones before it have it set.
+	buffer[i] = byte(value & 0x7F)
+
+	for value >>= 7; value > 0; value >>= 7 {
+		i--
+		buffer[i] = byte(value&0x7F) | 0x80
+	}
+
+	_, err := writer.Write(buffer[i:])
+
+	return err
+}
+
+// writeChunkHeader writes a chunk header.
+func writeChunkHeader(writer io.Writer, chunk ChunkHeader) error {
+	if len(chunk.ChunkType) != 4 {
+		return BadSizeChunk
+	}
+
+	_, err := io.WriteString(writer, chunk.ChunkType)
+
+	if err != nil {
+		return err
+	}
+
+	return writeUint32(writer, chunk.Length)
+}
+
+// writeHeaderData writes SMF-header chunk header data.
+func writeHeaderData(writer io.Writer, headerData HeaderData) error {
+	// Should be one of 0, 1, 2
+	if headerData.Format > 2 {
+		return UnsupportedSmfFormat
+	}
+
+	var division uint16
+
+	if headerData.TimeFormat == TimeCodeTimeFormat {
+		if !validFrameRate(headerData.FrameRate) {
+			return UnsupportedTimeCodeFormat
+		}
+
+		// The upper byte is the negative frame rate, the lower byte the ticks per frame.
+		division = uint16(uint8(-int8(headerData.FrameRate)))<<8 | uint16(headerData.TicksPerFrame)
+	} else {
+		division = headerData.TicksPerQuarterNote & 0x7FFF
+	}
+
+	var err = writeUint16(writer, headerData.Format)
+
+	if err != nil {
+		return err
+	}
+
+	err = writeUint16(writer, headerData.NumTracks)
+
+	if err != nil {
+		return err
+	}
+
+	return writeUint16(writer, division)
+}
+
+// writeStatusByte writes the track event status byte from the type and channel.
+func writeStatusByte(writer io.Writer, messageType uint8, messageChannel uint8) error {
+	return writeUint8(writer, messageType<<4|messageChannel&0x0F)
+}
+
+// writeVarLengthData writes data preceded by its variable length.
+func writeVarLengthData(writer io.Writer, data []byte) error {
+	var err = writeVarLength(writer, uint32(len(data)))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(data)
+
+	return err
+}
+
+// writeText writes a string preceded by its variable length.
+func writeText(writer io.Writer, text string) error {
+	var err = writeVarLength(writer, uint32(len(text)))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, text)
+
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright 2012 Joe Wass. All rights reserved.
+// Use of this source code is governed by the MIT license
+// which can be found in the LICENSE file.
+
+// MIDI package
+// A package for reading Standard Midi Files, written in Go.
+// Joe Wass 2012
+// joe@afandian.com
+
+/*
+ * Tests for the write functions.
+ * Each should write what the matching parse function reads.
+ */
+
+package midi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Test that writeVarLength writes the same examples that parseVarLength reads.
+// Example data taken from http://www.music.mcgill.ca/~ich/classes/mumt306/midiformat.pdf
+func TestVarLengthWriter(t *testing.T) {
+	input := []uint32{
+		0x00000000,
+		0x00000040,
+		0x0000007F,
+		0x00000080,
+		0x00002000,
+		0x00003FFF,
+		0x00004000,
+		0x00100000,
+		0x001FFFFF,
+		0x00200000,
+		0x08000000,
+		0x0FFFFFFF}
+
+	expected := [][]byte{
+		[]byte{0x00},
+		[]byte{0x40},
+		[]byte{0x7F},
+		[]byte{0x81, 0x00},
+		[]byte{0xC0, 0x00},
+		[]byte{0xFF, 0x7F},
+		[]byte{0x81, 0x80, 0x00},
+		[]byte{0xC0, 0x80, 0x00},
+		[]byte{0xFF, 0xFF, 0x7F},
+		[]byte{0x81, 0x80, 0x80, 0x00},
+		[]byte{0xC0, 0x80, 0x80, 0x00},
+		[]byte{0xFF, 0xFF, 0xFF, 0x7F}}
+
+	for i := 0; i < len(input); i++ {
+		var buffer bytes.Buffer
+
+		assertNoError(writeVarLength(&buffer, input[i]), t)
+		assertBytesEqual(buffer.Bytes(), expected[i], t)
+	}
+
+	// Too big to fit in 4 bytes.
+	var buffer bytes.Buffer
+	assertError(writeVarLength(&buffer, 0x10000000), VarLengthTooBig, t)
+}
+
+// Test that the fixed size ints are written big-endian.
+func TestWriteInts(t *testing.T) {
+	var buffer bytes.Buffer
+
+	assertNoError(writeUint32(&buffer, 0x01020304), t)
+	assertNoError(writeUint24(&buffer, 0x050607), t)
+	assertNoError(writeUint16(&buffer, 0x0809), t)
+	assertNoError(writeUint8(&buffer, 0x0A), t)
+
+	assertBytesEqual(buffer.Bytes(), []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A}, t)
+
+	// Data bytes can't have the high bit set.
+	assertError(writeUint7(&buffer, 0x80), BadDataByte, t)
+	assertError(writeTwoUint7(&buffer, 0x00, 0x80), BadDataByte, t)
+}
+
+// Test that header data is written as it's read, for both time formats.
+func TestWriteHeaderData(t *testing.T) {
+	inputs := [][]byte{
+		[]byte{0x00, 0x01, 0x00, 0x02, 0x01, 0xE0},
+		[]byte{0x00, 0x00, 0x00, 0x01, 0xE7, 0x28},
+		[]byte{0x00, 0x02, 0x00, 0x03, 0xE3, 0x50},
+	}
+
+	for _, input := range inputs {
+		header, err := parseHeaderData(NewMockReadSeeker(&input))
+		assertNoError(err, t)
+
+		var buffer bytes.Buffer
+		assertNoError(writeHeaderData(&buffer, header), t)
+		assertBytesEqual(buffer.Bytes(), input, t)
+	}
+
+	var buffer bytes.Buffer
+	assertError(writeHeaderData(&buffer, HeaderData{Format: 3}), UnsupportedSmfFormat, t)
+	assertError(writeHeaderData(&buffer, HeaderData{TimeFormat: TimeCodeTimeFormat, FrameRate: 26}), UnsupportedTimeCodeFormat, t)
+}
+
+// Test that chunk headers are written as they're read.
+func TestWriteChunkHeader(t *testing.T) {
+	var buffer bytes.Buffer
+
+	assertNoError(writeChunkHeader(&buffer, ChunkHeader{ChunkType: "MTrk", Length: 0x0102}), t)
+	assertBytesEqual(buffer.Bytes(), []byte{0x4D, 0x54, 0x72, 0x6B, 0x00, 0x00, 0x01, 0x02}, t)
+
+	assertError(writeChunkHeader(&buffer, ChunkHeader{ChunkType: "MTr"}), BadSizeChunk, t)
+}
+
+// Test that pitch wheel values are the reverse of pitchWheelValue.
+func TestEncodePitchWheelValue(t *testing.T) {
+	values := []int16{-0x2000, -1, 0, 1, 0x1FFF}
+
+	for _, value := range values {
+		leastSignificant, mostSignificant := encodePitchWheelValue(value)
+		result, _ := pitchWheelValue(leastSignificant, mostSignificant)
+
+		assertInt16sEqual(result, value, t)
+	}
+}
+
+// Test that strings and data are written with their length.
+func TestWriteVarLengthData(t *testing.T) {
+	var buffer bytes.Buffer
+
+	assertNoError(writeText(&buffer, "Hello"), t)
+	assertNoError(writeVarLengthData(&buffer, []byte{0x01, 0x02}), t)
+	assertNoError(writeVarLengthData(&buffer, nil), t)
+
+	assertBytesEqual(buffer.Bytes(), []byte{0x05, 0x48, 0x65, 0x6C, 0x6C, 0x6F, 0x02, 0x01, 0x02, 0x00}, t)
+}
@@ -19,53 +19,8 @@ import (
 )
 
 // A mock implementation of LexerCallback that does nothing.
-type MockLexerCallback struct{}
-
-func (*MockLexerCallback) Header(header HeaderData)                                        {}
-func (*MockLexerCallback) Track(header ChunkHeader)                                        {}
-func (*MockLexerCallback) Began()                                                          {}
-func (*MockLexerCallback) Finished()                                                       {}
-func (*MockLexerCallback) ErrorReading()                                                   {}
-func (*MockLexerCallback) ErrorOpeningFile()                                               {}
-func (*MockLexerCallback) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) {}
-func (*MockLexerCallback) NoteOn(channel uint8, pitch uint8, velocity uint8, time uint32)  {}
-func (*MockLexerCallback) PolyphonicAfterTouch(channel uint8, pitch uint8, pressure uint8, time uint32) {
-}
-func (*MockLexerCallback) ControlChange(channel uint8, controller uint8, value uint8, time uint32) {}
-func (*MockLexerCallback) ProgramChange(channel uint8, program uint8, time uint32)                 {}
-func (*MockLexerCallback) ChannelAfterTouch(channel uint8, value uint8, time uint32)               {}
-func (*MockLexerCallback) PitchWheel(channel uint8, value int16, absValue uint16, time uint32)     {}
-func (*MockLexerCallback) TimeCodeQuarter(messageType uint8, values uint8, time uint32)            {}
-func (*MockLexerCallback) Tempo(bpm uint32, microsecondsPerCrotchet uint32, time uint32)           {}
-func (*MockLexerCallback) SongPositionPointer(beats uint16, time uint32)                           {}
-func (*MockLexerCallback) SongSelect(song uint8, time uint32)                                      {}
-func (*MockLexerCallback) Undefined1(time uint32)                                                  {}
-func (*MockLexerCallback) Undefined2(time uint32)                                                  {}
-func (*MockLexerCallback) TuneRequest(time uint32)                                                 {}
-func (*MockLexerCallback) TimingClock(time uint32)                                                 {}
-func (*MockLexerCallback) Undefined3(time uint32)                                                  {}
-func (*MockLexerCallback) Start(time uint32)                                                       {}
-func (*MockLexerCallback) Continue(time uint32)                                                    {}
-func (*MockLexerCallback) Stop(time uint32)                                                        {}
-func (*MockLexerCallback) Undefined4(time uint32)                                                  {}
-func (*MockLexerCallback) ActiveSensing(time uint32)                                               {}
-func (*MockLexerCallback) Reset(time uint32)                                                       {}
-func (*MockLexerCallback) Done(time uint32)                                                        {}
-
-func (*MockLexerCallback) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
-}
-func (*MockLexerCallback) Text(channel uint8, text string, time uint32)                {}
-func (*MockLexerCallback) CopyrightText(channel uint8, text string, time uint32)       {}
-func (*MockLexerCallback) SequenceName(channel uint8, text string, time uint32)        {}
-func (*MockLexerCallback) TrackInstrumentName(channel uint8, text string, time uint32) {}
-func (*MockLexerCallback) LyricText(channel uint8, text string, time uint32)           {}
-func (*MockLexerCallback) MarkerText(channel uint8, text string, time uint32)          {}
-func (*MockLexerCallback) CuePointText(channel uint8, text string, time uint32)        {}
-func (*MockLexerCallback) EndOfTrack(channel uint8, time uint32)                       {}
-func (*MockLexerCallback) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
-}
-func (*MockLexerCallback) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8) {
-
+type MockLexerCallback struct {
+	BaseLexerCallback
 }
 
 // A mock implementation of LexerCallback that counts each method call and stores the most recent values,
@@ -111,6 +66,17 @@ type CountingLexerCallback struct {
 	cuePointText         int
 	sequenceNumber       int
 	tempo                int
+	sysEx                int
+	sysExContinuation    int
+	escapeSequence       int
+	sequencerSpecific    int
+	unknownMeta          int
+	smpteOffset          int
+	keySignature         int
+	beforeEvent          int
+	channelPrefix        int
+	midiPort             int
+	unknownChunk         int
 
 	// Most recent values
 	headerData  HeaderData
@@ -121,6 +87,12 @@ type CountingLexerCallback struct {
 	velocity    uint8
 	pressure    uint8
 	textValue   string
+	data        []byte
+
+	// Meta event args
+	metaType       uint8
+	port           uint8
+	manufacturerID []byte
 
 	pitchWheelValue         int16
 	pitchWheelValueAbsolute uint16
@@ -133,18 +105,40 @@ type CountingLexerCallback struct {
 	clocksPerClick           uint8
 	demiSemiQuaverPerQuarter uint8
 
+	// SMPTE Offset args
+	frameRate        SMPTEFrameRate
+	hours            uint8
+	minutes          uint8
+	seconds          uint8
+	frames           uint8
+	fractionalFrames uint8
+
 	// Tempo
 
 	microsecondsPerCrotchet uint32
 	bpm                     uint32
+
+	// Key signature args
+	key           ScaleDegree
+	mode          KeySignatureMode
+	sharpsOrFlats int8
+
+	// Most recent event context
+	context EventContext
+
+	// Most recent error
+	errorValue error
 }
 
 func (cbk *CountingLexerCallback) Header(header HeaderData) { cbk.header++; cbk.headerData = header }
 func (cbk *CountingLexerCallback) Track(header ChunkHeader) { cbk.track++; cbk.chunkHeader = header }
 func (cbk *CountingLexerCallback) Began()                   { cbk.began++ }
 func (cbk *CountingLexerCallback) Finished()                { cbk.finished++ }
-func (cbk *CountingLexerCallback) ErrorReading()            { cbk.errorReading++ }
-func (cbk *CountingLexerCallback) ErrorOpeningFile()        { cbk.errorOpeningFile++ }
+func (cbk *CountingLexerCallback) ErrorReading(err error) {
+	cbk.errorReading++
+	cbk.errorValue = err
+}
+func (cbk *CountingLexerCallback) ErrorOpeningFile() { cbk.errorOpeningFile++ }
 func (cbk *CountingLexerCallback) NoteOff(channel uint8, pitch uint8, velocity uint8, time uint32) {
 	cbk.noteOff++
 	cbk.pitch = pitch
@@ -214,6 +208,22 @@ func (cbk *CountingLexerCallback) ActiveSensing(time uint32)          { cbk.acti
 func (cbk *CountingLexerCallback) Reset(time uint32)                  { cbk.reset++ }
 func (cbk *CountingLexerCallback) Done(time uint32)                   { cbk.done++ }
 
+func (cbk *CountingLexerCallback) SysEx(data []byte, time uint32) {
+	cbk.sysEx++
+	cbk.data = data
+	cbk.time = time
+}
+func (cbk *CountingLexerCallback) SysExContinuation(data []byte, time uint32) {
+	cbk.sysExContinuation++
+	cbk.data = data
+	cbk.time = time
+}
+func (cbk *CountingLexerCallback) EscapeSequence(data []byte, time uint32) {
+	cbk.escapeSequence++
+	cbk.data = data
+	cbk.time = time
+}
+
 func (cbk *CountingLexerCallback) SequenceNumber(channel uint8, number uint16, numberGiven bool, time uint32) {
 	cbk.sequenceNumber++
 	cbk.time = time
@@ -259,6 +269,21 @@ func (cbk *CountingLexerCallback) EndOfTrack(channel uint8, time uint32) {
 	cbk.endOfTrack++
 	cbk.time = time
 }
+func (cbk *CountingLexerCallback) ChannelPrefix(channel uint8, time uint32) {
+	cbk.channelPrefix++
+	cbk.channel = channel
+	cbk.time = time
+}
+func (cbk *CountingLexerCallback) MidiPort(port uint8, time uint32) {
+	cbk.midiPort++
+	cbk.port = port
+	cbk.time = time
+}
+func (cbk *CountingLexerCallback) UnknownChunk(header ChunkHeader, data []byte) {
+	cbk.unknownChunk++
+	cbk.chunkHeader = header
+	cbk.data = data
+}
 func (cbk *CountingLexerCallback) TimeSignature(numerator uint8, denomenator uint8, clocksPerClick uint8, demiSemiQuaverPerQuarter uint8, time uint32) {
 	cbk.numerator = numerator
 	cbk.denomenator = denomenator
@@ -267,8 +292,42 @@ func (cbk *CountingLexerCallback) TimeSignature(numerator uint8, denomenator uin
 	cbk.time = time
 }
 
-func (*CountingLexerCallback) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8) {
-	// TODO fill out when tests written.
+func (cbk *CountingLexerCallback) KeySignature(key ScaleDegree, mode KeySignatureMode, sharpsOrFlats int8, time uint32) {
+	cbk.keySignature++
+	cbk.key = key
+	cbk.mode = mode
+	cbk.sharpsOrFlats = sharpsOrFlats
+	cbk.time = time
+}
+
+func (cbk *CountingLexerCallback) BeforeEvent(context EventContext) {
+	cbk.beforeEvent++
+	cbk.context = context
+}
+
+func (cbk *CountingLexerCallback) SMPTEOffset(frameRate SMPTEFrameRate, hours uint8, minutes uint8, seconds uint8, frames uint8, fractionalFrames uint8, time uint32) {
+	cbk.smpteOffset++
+	cbk.frameRate = frameRate
+	cbk.hours = hours
+	cbk.minutes = minutes
+	cbk.seconds = seconds
+	cbk.frames = frames
+	cbk.fractionalFrames = fractionalFrames
+	cbk.time = time
+}
+
+func (cbk *CountingLexerCallback) SequencerSpecific(manufacturerID []byte, data []byte, time uint32) {
+	cbk.sequencerSpecific++
+	cbk.manufacturerID = manufacturerID
+	cbk.data = data
+	cbk.time = time
+}
+
+func (cbk *CountingLexerCallback) UnknownMeta(metaType uint8, data []byte, time uint32) {
+	cbk.unknownMeta++
+	cbk.metaType = metaType
+	cbk.data = data
+	cbk.time = time
 }
 
 // MockReadSeeker is a mock Reader and Seeker. Constructed with data, behaves as a file reader.